@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/services"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+const usage = `Usage: members <command>
+
+Commands:
+  invite [-uses N] [-expires DURATION] [-name NAME]
+            Create an invite code (default: single use, expires in 7 days)
+  invites   List invite codes
+  revoke CODE
+            Delete an invite code
+  pending   List members waiting for approval
+  approve NAME
+            Approve (verify) a member
+  role NAME ROLE
+            Set a member's role (member, moderator or admin)`
+
+func main() {
+	// Configure logger to write to stdout
+	log.SetOutput(os.Stdout)
+
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	// Load .env file during development only
+	// In production, use environment variables that are already set
+	appEnv := os.Getenv("APP_ENV")
+	if appEnv != "production" {
+		if err := utils.LoadEnvFile(); err != nil {
+			log.Printf("Note: Could not load .env file (this is normal in production): %v", err)
+		}
+	}
+
+	if err := utils.InitDatabase(); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer utils.CloseDatabase()
+
+	globalServices := services.NewServices(utils.GetDB())
+
+	switch os.Args[1] {
+	case "invite":
+		flags := flag.NewFlagSet("invite", flag.ExitOnError)
+		uses := flags.Int("uses", 1, "number of members that can register with the code")
+		expires := flags.Duration("expires", 7*24*time.Hour, "how long the code is valid for (0 for no expiry)")
+		name := flags.String("name", "", "only allow registering this member name")
+		flags.Parse(os.Args[2:])
+
+		req := &model.CreateInviteCodeRequest{PresetName: *name, MaxUses: *uses}
+		if *expires > 0 {
+			expiresAt := time.Now().Add(*expires)
+			req.ExpiresAt = &expiresAt
+		}
+
+		invite, err := globalServices.Invite.CreateInviteCode(0, req)
+		if err != nil {
+			log.Fatalf("Failed to create invite code: %v", err)
+		}
+		fmt.Printf("Invite code: %s\n", invite.Code)
+		fmt.Printf("Register at: /register?code=%s\n", invite.Code)
+
+	case "invites":
+		invites, err := globalServices.Invite.GetAllInviteCodes()
+		if err != nil {
+			log.Fatalf("Failed to get invite codes: %v", err)
+		}
+		for _, invite := range invites {
+			presetName := "-"
+			if invite.PresetName != nil {
+				presetName = *invite.PresetName
+			}
+			expires := "never"
+			if invite.ExpiresAt != nil {
+				expires = invite.ExpiresAt.Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%s  uses %d/%d  expires %-16s  name %s\n", invite.Code, invite.Uses, invite.MaxUses, expires, presetName)
+		}
+
+	case "revoke":
+		if len(os.Args) < 3 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		if err := globalServices.Invite.DeleteInviteCode(os.Args[2]); err != nil {
+			log.Fatalf("Failed to revoke invite code: %v", err)
+		}
+		log.Printf("Revoked invite code %s", os.Args[2])
+
+	case "pending":
+		members, err := globalServices.Member.GetUnverifiedMembers()
+		if err != nil {
+			log.Fatalf("Failed to get members awaiting approval: %v", err)
+		}
+		for _, member := range members {
+			fmt.Printf("%-30s registered %s\n", member.Name, member.CreatedAt.Format("2006-01-02 15:04"))
+		}
+
+	case "approve":
+		if len(os.Args) < 3 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		member, err := globalServices.Member.GetMemberByName(os.Args[2])
+		if err != nil {
+			log.Fatalf("Failed to get member %s: %v", os.Args[2], err)
+		}
+		if err := globalServices.Member.VerifyMember(member.ID); err != nil {
+			log.Fatalf("Failed to approve member %s: %v", member.Name, err)
+		}
+		log.Printf("Approved member %s", member.Name)
+
+	case "role":
+		if len(os.Args) < 4 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		member, err := globalServices.Member.GetMemberByName(os.Args[2])
+		if err != nil {
+			log.Fatalf("Failed to get member %s: %v", os.Args[2], err)
+		}
+		if err := globalServices.Member.SetRole(member.ID, os.Args[3]); err != nil {
+			log.Fatalf("Failed to set role for member %s: %v", member.Name, err)
+		}
+		log.Printf("Member %s is now %s", member.Name, os.Args[3])
+
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  status    Show applied and pending migrations
+  up        Apply all pending migrations
+  down N    Revert the N most recently applied migrations
+  redo      Revert and re-apply the most recently applied migration`
+
+func main() {
+	// Configure logger to write to stdout
+	log.SetOutput(os.Stdout)
+
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	// Load .env file during development only
+	// In production, use environment variables that are already set
+	appEnv := os.Getenv("APP_ENV")
+	if appEnv != "production" {
+		if err := utils.LoadEnvFile(); err != nil {
+			log.Printf("Note: Could not load .env file (this is normal in production): %v", err)
+		}
+	}
+
+	// Connect without applying migrations automatically
+	if err := utils.OpenDatabase(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer utils.CloseDatabase()
+
+	db := utils.GetDB()
+
+	switch os.Args[1] {
+	case "status":
+		statuses, err := utils.GetMigrationStatus(db)
+		if err != nil {
+			log.Fatalf("Failed to get migration status: %v", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d_%-40s %s\n", status.Version, status.Name, state)
+		}
+
+	case "up":
+		applied, err := utils.MigrateUp(db)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Printf("Applied %d migrations", applied)
+
+	case "down":
+		if len(os.Args) < 3 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		steps, err := strconv.Atoi(os.Args[2])
+		if err != nil || steps <= 0 {
+			log.Fatalf("Invalid number of migrations to revert: %q", os.Args[2])
+		}
+		reverted, err := utils.MigrateDown(db, steps)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Printf("Reverted %d migrations", reverted)
+
+	case "redo":
+		if err := utils.MigrateRedo(db); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Printf("Redid the most recent migration")
+
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
@@ -122,7 +122,10 @@ func createMember(services *services.Services, name, password string) error {
 	}
 
 	// Hash the password
-	passwordHash := utils.HashPassword(password)
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
 
 	// Create the member using the regular CreateMember method
 	member, err := services.Member.CreateMember(name, passwordHash)
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/sb-luis/creative-coding-bookclub/internal/routes"
+	"github.com/sb-luis/creative-coding-bookclub/internal/scheduler"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services"
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
 )
@@ -13,6 +20,10 @@ import (
 var globalServices *services.Services
 
 func main() {
+	dev := flag.Bool("dev", false, "read templates, locales and assets from disk and reload them on change")
+	webDir := flag.String("web-dir", "web", "directory of the web files in dev mode")
+	flag.Parse()
+
 	// Configure logger to write to stdout
 	log.SetOutput(os.Stdout)
 
@@ -25,6 +36,16 @@ func main() {
 		}
 	}
 
+	// Log JSON lines at the level set by LOG_LEVEL
+	utils.InitLogger()
+
+	// Web files are embedded in the binary, unless developing
+	if *dev {
+		if err := utils.UseWebDir(*webDir); err != nil {
+			log.Fatalf("Failed to open web directory %s: %v", *webDir, err)
+		}
+	}
+
 	// Initialize i18n
 	utils.I18nInit()
 
@@ -36,25 +57,77 @@ func main() {
 	// Initialize services
 	globalServices = services.NewServices(utils.GetDB())
 
-	// Ensure database is properly closed on shutdown
-	defer func() {
-		if err := utils.CloseDatabase(); err != nil {
-			log.Printf("Error closing database: %v", err)
-		}
-	}()
+	// Start background housekeeping jobs
+	jobScheduler := scheduler.New(utils.GetDB())
+	if err := scheduler.RegisterHousekeepingJobs(jobScheduler, globalServices); err != nil {
+		log.Fatal("Failed to register background jobs:", err)
+	}
+	jobScheduler.Start()
 
 	// Create a new custom router
 	router := utils.NewRouter()
 
 	routes.RegisterRoutes(router, globalServices)
 
+	// Print the route table, in matching order, when debugging routing
+	if os.Getenv("DEBUG_ROUTES") == "true" {
+		router.DumpRoutes(os.Stdout)
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8000" // Default to port 8000 if PORT is not set
 	}
 
-	log.Printf("Starting server on :%s\n", port)
-	if err := http.ListenAndServe(":"+port, router); err != nil {
-		log.Fatal("Server failed to start:", err)
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           router,
+		ReadHeaderTimeout: utils.GetEnvDuration("HTTP_READ_HEADER_TIMEOUT", 10*time.Second),
+		ReadTimeout:       utils.GetEnvDuration("HTTP_READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:      utils.GetEnvDuration("HTTP_WRITE_TIMEOUT", 60*time.Second),
+		IdleTimeout:       utils.GetEnvDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+	}
+	shutdownGracePeriod := utils.GetEnvDuration("SHUTDOWN_GRACE_PERIOD", 20*time.Second)
+
+	// Stop on Ctrl+C locally and on SIGTERM when the container is stopped
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting server on :%s\n", port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		// ListenAndServe only returns early if the server could not start or failed
+		log.Printf("Server failed to start: %v", err)
+		exitCode = 1
+	case <-ctx.Done():
+		stop() // A second signal kills the process straight away
+		log.Printf("Shutting down, waiting up to %s for open requests to finish", shutdownGracePeriod)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error draining connections: %v", err)
+			exitCode = 1
+		}
+		cancel()
+		if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Server error: %v", err)
+		}
 	}
+
+	// Stop background jobs before closing the database they use
+	jobScheduler.Stop()
+
+	if err := utils.CloseDatabase(); err != nil {
+		log.Printf("Error closing database: %v", err)
+		exitCode = 1
+	}
+
+	log.Printf("Server stopped")
+	os.Exit(exitCode)
 }
@@ -2,13 +2,17 @@ module github.com/sb-luis/creative-coding-bookclub
 
 go 1.23.4
 
-require github.com/jackc/pgx/v5 v5.7.1
+require (
+	github.com/evanw/esbuild v0.28.1
+	github.com/jackc/pgx/v5 v5.7.1
+	golang.org/x/crypto v0.27.0
+)
 
 require (
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
-	golang.org/x/crypto v0.27.0 // indirect
 	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/sys v0.25.0 // indirect
 	golang.org/x/text v0.18.0 // indirect
 )
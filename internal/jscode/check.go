@@ -0,0 +1,66 @@
+// Package jscode checks and formats the JavaScript source code of sketches
+package jscode
+
+import (
+	"unicode/utf8"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"   // The code does not parse, so the sketch cannot run
+	SeverityWarning = "warning" // The code parses, but probably does not do what was meant
+)
+
+// Diagnostic is a problem found in the source code, at a 1-based line and column, or at line
+// and column 0 when it is about the code as a whole. Columns count characters, as the editor
+// does, not bytes.
+type Diagnostic struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+// Check parses the source code and returns its syntax errors and warnings
+func Check(source string) []Diagnostic {
+	result := api.Transform(source, api.TransformOptions{
+		Loader:     api.LoaderJS,
+		Sourcefile: "sketch.js",
+		LogLevel:   api.LogLevelSilent,
+	})
+
+	diagnostics := make([]Diagnostic, 0, len(result.Errors)+len(result.Warnings))
+	for _, message := range result.Errors {
+		diagnostics = append(diagnostics, newDiagnostic(message, SeverityError))
+	}
+	for _, message := range result.Warnings {
+		diagnostics = append(diagnostics, newDiagnostic(message, SeverityWarning))
+	}
+	return diagnostics
+}
+
+// HasErrors reports whether any of the diagnostics is an error
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// newDiagnostic converts an esbuild message, whose columns are 0-based byte offsets
+func newDiagnostic(message api.Message, severity string) Diagnostic {
+	diagnostic := Diagnostic{Message: message.Text, Severity: severity}
+	if location := message.Location; location != nil {
+		column := location.Column
+		if column > len(location.LineText) {
+			column = len(location.LineText)
+		}
+		diagnostic.Line = location.Line
+		diagnostic.Column = utf8.RuneCountInString(location.LineText[:column]) + 1
+	}
+	return diagnostic
+}
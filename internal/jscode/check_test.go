@@ -0,0 +1,14 @@
+package jscode
+
+import "testing"
+
+func TestCheckColumnsCountCharacters(t *testing.T) {
+	// esbuild reports byte offsets; the editor counts characters
+	diagnostics := Check("const café = 'é' +;")
+	if !HasErrors(diagnostics) {
+		t.Fatalf("Check reported no errors: %v", diagnostics)
+	}
+	if got := diagnostics[0]; got.Line != 1 || got.Column != 19 {
+		t.Errorf("error at %d:%d, want 1:19", got.Line, got.Column)
+	}
+}
@@ -0,0 +1,287 @@
+package jscode
+
+import (
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// indentUnit is the indentation of each nesting level
+const indentUnit = "  "
+
+// Format re-indents valid source code by its nesting of brackets, trims trailing spaces and
+// keeps at most one blank line in a row. Comments, strings and the lines inside multi-line
+// template literals and block comments are left as they are.
+// Code with syntax errors is returned unchanged, along with the diagnostics.
+func Format(source string) (string, []Diagnostic) {
+	diagnostics := Check(source)
+	if HasErrors(diagnostics) {
+		return source, diagnostics
+	}
+
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	lines := scanLines(source)
+
+	var b strings.Builder
+	var openers []int // For each open bracket, the indentation level of its line
+	blankLines := 0
+	for _, line := range lines {
+		level := 0
+		if n := len(openers); n > 0 {
+			level = openers[n-1] + 1
+			// A line starting with closing brackets lines up with the line that opened them
+			if line.leadingClosers > 0 {
+				level = openers[max(n-line.leadingClosers, 0)]
+			}
+		}
+		// Method chains continue the previous line
+		trimmed := strings.TrimSpace(line.text)
+		if strings.HasPrefix(trimmed, ".") && !strings.HasPrefix(trimmed, "...") && line.leadingClosers == 0 {
+			level++
+		}
+
+		for _, bracket := range line.brackets {
+			if isOpener(bracket) {
+				openers = append(openers, level)
+			} else if len(openers) > 0 {
+				openers = openers[:len(openers)-1]
+			}
+		}
+
+		if line.verbatim {
+			blankLines = 0
+			b.WriteString(line.text)
+			b.WriteByte('\n')
+			continue
+		}
+		if trimmed == "" {
+			blankLines++
+			continue
+		}
+
+		// Blank lines are written before the next line, so that they are dropped at the start and end
+		if blankLines > 0 && b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		blankLines = 0
+		b.WriteString(strings.Repeat(indentUnit, level))
+		b.WriteString(trimmed)
+		b.WriteByte('\n')
+	}
+
+	// Only whitespace should have changed. In case the scanner misread the code, for example taking
+	// a division for a regular expression, check it with esbuild rather than change what it does.
+	formatted := b.String()
+	if minifiedCode(formatted) != minifiedCode(source) {
+		return source, append(diagnostics, Diagnostic{
+			Message:  "The code could not be formatted without changing it, so it was left as it is",
+			Severity: SeverityWarning,
+		})
+	}
+
+	return formatted, diagnostics
+}
+
+// minifiedCode returns the code without comments and without whitespace outside strings,
+// or an empty string if it does not parse
+func minifiedCode(source string) string {
+	result := api.Transform(source, api.TransformOptions{
+		Loader:           api.LoaderJS,
+		MinifyWhitespace: true,
+		LegalComments:    api.LegalCommentsNone,
+		LogLevel:         api.LogLevelSilent,
+	})
+	if len(result.Errors) > 0 {
+		return ""
+	}
+	return string(result.Code)
+}
+
+// sourceLine is a line of source code with the brackets that matter for indentation
+type sourceLine struct {
+	text           string
+	verbatim       bool   // Starts inside a template literal, string or block comment
+	leadingClosers int    // Closing brackets before anything else on the line
+	brackets       []byte // Brackets outside strings, comments and regular expressions, in order
+}
+
+// Lexer states between characters
+const (
+	stateCode = iota
+	stateLineComment
+	stateBlockComment
+	stateString
+	stateTemplate
+	stateRegExp
+)
+
+// lineScanner finds the brackets of each line, carrying its state across lines
+type lineScanner struct {
+	state        int
+	quote        byte  // Quote of the current string
+	inClass      bool  // Inside [...] in a regular expression
+	braces       int   // Open braces in code
+	templates    []int // Brace count where each enclosing template literal's ${ was opened
+	regExpStarts bool  // Whether a / in code would start a regular expression rather than divide
+}
+
+// regExpKeywords are the keywords after which a / starts a regular expression
+var regExpKeywords = map[string]bool{
+	"await": true, "case": true, "delete": true, "do": true, "else": true, "in": true,
+	"instanceof": true, "new": true, "of": true, "return": true, "throw": true,
+	"typeof": true, "void": true, "yield": true,
+}
+
+// scanLines splits the source into lines and finds the brackets of each
+func scanLines(source string) []sourceLine {
+	s := &lineScanner{regExpStarts: true}
+	texts := strings.Split(source, "\n")
+	lines := make([]sourceLine, 0, len(texts))
+	for _, text := range texts {
+		line := sourceLine{text: text, verbatim: s.state != stateCode}
+		s.scan(&line)
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// scan finds the brackets of one line
+func (s *lineScanner) scan(line *sourceLine) {
+	text := line.text
+	leading := !line.verbatim
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch s.state {
+		case stateLineComment:
+			// Runs to the end of the line
+			i = len(text)
+
+		case stateBlockComment:
+			if c == '*' && i+1 < len(text) && text[i+1] == '/' {
+				s.state = stateCode
+				i++
+			}
+
+		case stateString:
+			if c == '\\' {
+				i++
+			} else if c == s.quote {
+				s.state = stateCode
+				s.regExpStarts = false
+			}
+
+		case stateTemplate:
+			if c == '\\' {
+				i++
+			} else if c == '`' {
+				s.state = stateCode
+				s.regExpStarts = false
+			} else if c == '$' && i+1 < len(text) && text[i+1] == '{' {
+				s.templates = append(s.templates, s.braces)
+				s.state = stateCode
+				s.regExpStarts = true
+				line.brackets = append(line.brackets, '{')
+				i++
+			}
+
+		case stateRegExp:
+			if c == '\\' {
+				i++
+			} else if c == '[' {
+				s.inClass = true
+			} else if c == ']' {
+				s.inClass = false
+			} else if c == '/' && !s.inClass {
+				s.state = stateCode
+				s.regExpStarts = false
+			}
+
+		case stateCode:
+			if c == ' ' || c == '\t' {
+				continue
+			}
+			if leading && !isCloser(c) {
+				leading = false
+			}
+
+			switch {
+			case c == '/' && i+1 < len(text) && text[i+1] == '/':
+				s.state = stateLineComment
+				i = len(text)
+			case c == '/' && i+1 < len(text) && text[i+1] == '*':
+				s.state = stateBlockComment
+				i++
+			case c == '/':
+				if s.regExpStarts {
+					s.state = stateRegExp
+					s.inClass = false
+				} else {
+					s.regExpStarts = true
+				}
+			case (c == '+' || c == '-') && i+1 < len(text) && text[i+1] == c:
+				// After x++ or x-- comes division; before ++x or --x a / cannot come at all
+				s.regExpStarts = false
+				i++
+			case c == '\'' || c == '"':
+				s.state = stateString
+				s.quote = c
+			case c == '`':
+				s.state = stateTemplate
+			case isIdentifierChar(c):
+				start := i
+				for i+1 < len(text) && isIdentifierChar(text[i+1]) {
+					i++
+				}
+				s.regExpStarts = regExpKeywords[text[start:i+1]]
+			case c == '}' && len(s.templates) > 0 && s.templates[len(s.templates)-1] == s.braces:
+				// Closes a ${ expression, back inside the template literal
+				s.templates = s.templates[:len(s.templates)-1]
+				s.state = stateTemplate
+				line.brackets = append(line.brackets, c)
+				if leading {
+					line.leadingClosers++
+				}
+			case isOpener(c) || isCloser(c):
+				if c == '{' {
+					s.braces++
+				} else if c == '}' {
+					s.braces--
+				}
+				line.brackets = append(line.brackets, c)
+				if leading {
+					line.leadingClosers++
+				}
+				// Division after a value; a block closing brace before a regular expression is rare
+				s.regExpStarts = isOpener(c)
+			default:
+				s.regExpStarts = true
+			}
+		}
+	}
+
+	// Line comments and unterminated strings end with the line, but a string continued
+	// with a backslash at the end of the line goes on
+	if s.state == stateLineComment {
+		s.state = stateCode
+	}
+	if s.state == stateString && !strings.HasSuffix(text, "\\") {
+		s.state = stateCode
+	}
+}
+
+// isOpener reports whether a byte is an opening bracket
+func isOpener(c byte) bool {
+	return c == '{' || c == '(' || c == '['
+}
+
+// isCloser reports whether a byte is a closing bracket
+func isCloser(c byte) bool {
+	return c == '}' || c == ')' || c == ']'
+}
+
+// isIdentifierChar reports whether a byte can be part of an identifier, number or keyword.
+// Bytes of multi-byte UTF-8 characters count, as they can only appear in identifiers in code.
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
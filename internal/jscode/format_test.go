@@ -0,0 +1,164 @@
+package jscode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScanLinesBrackets(t *testing.T) {
+	// Reading a division as a regular expression, or the other way around, hides or exposes
+	// the brackets between two slashes, which is what indentation is worked out from
+	tests := []struct {
+		name string
+		line string
+		want string // Brackets found outside strings, comments and regular expressions
+	}{
+		// Division
+		{"division after )", "x = f(a) / g(b) / 2", "()()"},
+		{"division after ]", "x = a[0] / g(b) / 2", "[]()"},
+		{"division after identifier", "x = a / g(b) / 2", "()"},
+		{"division after number", "x = 10 / g(b) / 2", "()"},
+		{"division after ++", "x = i++ / g(b) / 2", "()"},
+		{"division after --", "x = i-- / g(b) / 2", "()"},
+		{"division after string", "x = 'a' / g(b) / 2", "()"},
+
+		// Regular expressions
+		{"regexp after =", "r = /[{(]/g", ""},
+		{"regexp after (", "f(/[)]/)", "()"},
+		{"regexp after ,", "f(a, /[)]/)", "()"},
+		{"regexp after return", "return /[(]/.test(s)", "()"},
+		{"regexp after typeof", "x = typeof /[(]/", ""},
+		{"regexp after prefix ++", "x = ++i + /[(]/.source.length", ""},
+		{"regexp with / in a class", "r = /[/(]/; f(a)", "()"},
+		{"regexp with escaped /", "r = /\\/[(]/; f(a)", "()"},
+		{"regexp with //", "r = /[//(]/; f(a)", "()"},
+
+		// Strings and comments
+		{"// in a double-quoted string", `s = "http://example.com" + f(a)`, "()"},
+		{"// in a single-quoted string", `s = 'http://example.com' + f(a)`, "()"},
+		{"escaped quote in a string", `s = "a\"(" + f(a)`, "()"},
+		{"line comment", "f(a) // )", "()"},
+		{"block comment", "/* ( */ f(a)", "()"},
+
+		// Template literals
+		{"template text", "t = `(${x})`", "{}"},
+		{"template expression", "t = `${f(a)}`", "{()}"},
+		{"object in a template expression", "t = `${ {a: 1}.a }`", "{{}}"},
+		{"nested templates", "t = `a ${f(`b ${g(c)}`)} d`", "{({()})}"},
+		{"// in a template", "t = `http://${host}/` + f(a)", "{}()"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := scanLines(tt.line)
+			if got := string(lines[0].brackets); got != tt.want {
+				t.Errorf("scanLines(%q) found brackets %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			"indents blocks",
+			"function draw() {\nif (x) {\nline(0, 0, 10, 10);\n}\n}",
+			"function draw() {\n  if (x) {\n    line(0, 0, 10, 10);\n  }\n}\n",
+		},
+		{
+			"indents brackets",
+			"const points = [\n[0, 1],\n[2, 3],\n];\nf(\na,\nb\n);",
+			"const points = [\n  [0, 1],\n  [2, 3],\n];\nf(\n  a,\n  b\n);\n",
+		},
+		{
+			"closers line up with their opener",
+			"f(function () {\nx();\n});",
+			"f(function () {\n  x();\n});\n",
+		},
+		{
+			"method chains",
+			"p.fill(0)\n.stroke(255)\n.rect(0, 0, 1, 1);",
+			"p.fill(0)\n  .stroke(255)\n  .rect(0, 0, 1, 1);\n",
+		},
+		{
+			"trims spaces and blank lines",
+			"\n\nlet a = 1;   \n\n\n\nlet b = 2;\t\n\n",
+			"let a = 1;\n\nlet b = 2;\n",
+		},
+		{
+			"keeps multi-line templates",
+			"let a = i++ / 2;\nconst u = `https://example.com`;\nconst t = `\n    keep\n`;\nif (a) {\nf(t);\n}",
+			"let a = i++ / 2;\nconst u = `https://example.com`;\nconst t = `\n    keep\n`;\nif (a) {\n  f(t);\n}\n",
+		},
+		{
+			"keeps nested templates across lines",
+			"const html = `<ul>\n  ${items.map((item) => `\n    <li>${item}</li>\n  `).join('')}\n</ul>`;\nif (x) {\ny();\n}",
+			"const html = `<ul>\n  ${items.map((item) => `\n    <li>${item}</li>\n  `).join('')}\n</ul>`;\nif (x) {\n  y();\n}\n",
+		},
+		{
+			"keeps block comments",
+			"if (x) {\n/*\n   keep ( this\n*/\ny();\n}",
+			"if (x) {\n  /*\n   keep ( this\n*/\n  y();\n}\n",
+		},
+		{
+			"regexps do not count brackets",
+			"if (/[{]/.test(s)) {\nx = s.split(/[(]/);\n}",
+			"if (/[{]/.test(s)) {\n  x = s.split(/[(]/);\n}\n",
+		},
+		{
+			"normalizes line endings",
+			"if (x) {\r\ny();\r\n}\r\n",
+			"if (x) {\n  y();\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diagnostics := Format(tt.in)
+			if len(diagnostics) > 0 {
+				t.Fatalf("Format(%q) reported %v", tt.in, diagnostics)
+			}
+			if got != tt.want {
+				t.Errorf("Format(%q)\ngot  %q\nwant %q", tt.in, got, tt.want)
+			}
+			if again, _ := Format(got); again != got {
+				t.Errorf("formatting again changed %q into %q", got, again)
+			}
+		})
+	}
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+	in := "function draw() {\nline(0, 0,\n}"
+	got, diagnostics := Format(in)
+	if got != in {
+		t.Errorf("Format changed code with a syntax error into %q", got)
+	}
+	if !HasErrors(diagnostics) {
+		t.Fatalf("Format(%q) reported no errors: %v", in, diagnostics)
+	}
+	if diagnostics[0].Line != 3 || diagnostics[0].Column != 1 {
+		t.Errorf("error at %d:%d, want 3:1", diagnostics[0].Line, diagnostics[0].Column)
+	}
+}
+
+func TestFormatKeepsCodeItWouldChange(t *testing.T) {
+	// After a block's closing brace the scanner takes / for a division, so it misses that a
+	// regular expression starts, reads its ` as the start of a template literal and gets
+	// template literals backwards from there. Re-indenting would then change the string below,
+	// which the comparison of minified code catches.
+	in := "if (x) {}\n/`/.test(s);\nconst t = `\n    keep\n`;\nif (x) {\ny();\n}\n"
+	got, diagnostics := Format(in)
+	if got != in {
+		t.Errorf("Format changed the code into %q", got)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Severity != SeverityWarning ||
+		!strings.Contains(diagnostics[0].Message, "could not be formatted") {
+		t.Fatalf("Format reported %v, want a single warning that the code was left as it is", diagnostics)
+	}
+	if diagnostics[0].Line != 0 || diagnostics[0].Column != 0 {
+		t.Errorf("warning at %d:%d, want no location", diagnostics[0].Line, diagnostics[0].Column)
+	}
+}
@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics are kept in memory for the lifetime of the process and written out in the
+// Prometheus text exposition format, see https://prometheus.io/docs/instrumenting/exposition_formats/
+
+// durationBuckets are the upper bounds, in seconds, of the request latency histogram buckets
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// requestKey identifies the requests counted together: the route pattern is used rather
+// than the request path, so that /sketches/{memberName}/{sketchSlug} is a single series
+type requestKey struct {
+	method string
+	route  string
+	status int
+}
+
+// latencyKey identifies the requests sharing a latency histogram
+type latencyKey struct {
+	method string
+	route  string
+}
+
+// histogram counts observations per bucket, plus their count and sum
+type histogram struct {
+	buckets []uint64 // Not cumulative; one per entry in durationBuckets
+	count   uint64
+	sum     float64
+}
+
+var (
+	mu          sync.Mutex
+	requests    = map[requestKey]uint64{}
+	latencies   = map[latencyKey]*histogram{}
+	sketchSaves = map[string]uint64{}
+)
+
+// knownMethods are the request methods recorded under their own name
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodConnect: true,
+	http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// ObserveRequest records a handled HTTP request under its route pattern. Any method outside
+// the standard ones is recorded as OTHER, as clients can send whatever method they like and
+// each would otherwise add series that are kept forever.
+func ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	seconds := elapsed.Seconds()
+	if !knownMethods[method] {
+		method = "OTHER"
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	requests[requestKey{method: method, route: route, status: status}]++
+
+	key := latencyKey{method: method, route: route}
+	h, ok := latencies[key]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(durationBuckets))}
+		latencies[key] = h
+	}
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// IncSketchSaves counts a successful sketch save, by operation (create, update, fork, ...)
+func IncSketchSaves(operation string) {
+	mu.Lock()
+	defer mu.Unlock()
+	sketchSaves[operation]++
+}
+
+// Gauges are values read when the metrics are written, rather than counted as they happen
+type Gauges struct {
+	DB             sql.DBStats
+	ActiveSessions int
+}
+
+// Write writes all metrics in the Prometheus text format
+func Write(w io.Writer, gauges Gauges) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	writeHeader(w, "http_requests_total", "counter", "HTTP requests handled, by method, route pattern and status code.")
+	requestKeys := make([]requestKey, 0, len(requests))
+	for key := range requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		a, b := requestKeys[i], requestKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	for _, key := range requestKeys {
+		fmt.Fprintf(w, "http_requests_total{method=%s,route=%s,status=\"%d\"} %d\n",
+			quote(key.method), quote(key.route), key.status, requests[key])
+	}
+
+	writeHeader(w, "http_request_duration_seconds", "histogram", "HTTP request latency, by method and route pattern.")
+	latencyKeys := make([]latencyKey, 0, len(latencies))
+	for key := range latencies {
+		latencyKeys = append(latencyKeys, key)
+	}
+	sort.Slice(latencyKeys, func(i, j int) bool {
+		a, b := latencyKeys[i], latencyKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		return a.method < b.method
+	})
+	for _, key := range latencyKeys {
+		h := latencies[key]
+		labels := fmt.Sprintf("method=%s,route=%s", quote(key.method), quote(key.route))
+		var cumulative uint64
+		for i, bound := range durationBuckets {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, bound, cumulative)
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{%s} %g\n", labels, h.sum)
+		fmt.Fprintf(w, "http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	writeHeader(w, "bookclub_sketch_saves_total", "counter", "Sketches saved, by operation.")
+	operations := make([]string, 0, len(sketchSaves))
+	for operation := range sketchSaves {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	for _, operation := range operations {
+		fmt.Fprintf(w, "bookclub_sketch_saves_total{operation=%s} %d\n", quote(operation), sketchSaves[operation])
+	}
+
+	writeHeader(w, "bookclub_active_sessions", "gauge", "Sessions that have not expired.")
+	fmt.Fprintf(w, "bookclub_active_sessions %d\n", gauges.ActiveSessions)
+
+	db := gauges.DB
+	writeGauge(w, "db_max_open_connections", "Maximum number of open connections to the database (0 is unlimited).", float64(db.MaxOpenConnections))
+	writeGauge(w, "db_open_connections", "Established connections to the database, in use or idle.", float64(db.OpenConnections))
+	writeGauge(w, "db_in_use_connections", "Connections to the database currently in use.", float64(db.InUse))
+	writeGauge(w, "db_idle_connections", "Idle connections to the database.", float64(db.Idle))
+	writeCounter(w, "db_wait_count_total", "Times a query waited for a free database connection.", float64(db.WaitCount))
+	writeCounter(w, "db_wait_duration_seconds_total", "Time spent waiting for a free database connection.", db.WaitDuration.Seconds())
+	writeCounter(w, "db_max_idle_closed_total", "Connections closed because of the idle connection limit.", float64(db.MaxIdleClosed))
+	writeCounter(w, "db_max_idle_time_closed_total", "Connections closed because they were idle for too long.", float64(db.MaxIdleTimeClosed))
+	writeCounter(w, "db_max_lifetime_closed_total", "Connections closed because they reached their maximum lifetime.", float64(db.MaxLifetimeClosed))
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// writeGauge writes a gauge without labels
+func writeGauge(w io.Writer, name, help string, value float64) {
+	writeHeader(w, name, "gauge", help)
+	fmt.Fprintf(w, "%s %g\n", name, value)
+}
+
+// writeCounter writes a counter without labels
+func writeCounter(w io.Writer, name, help string, value float64) {
+	writeHeader(w, name, "counter", help)
+	fmt.Fprintf(w, "%s %g\n", name, value)
+}
+
+// labelEscaper escapes label values as the text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quote returns a label value in double quotes
+func quote(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+)
+
+// AdminStats holds the counts shown on the admin dashboard
+type AdminStats struct {
+	Members           int `json:"members"`
+	UnverifiedMembers int `json:"unverified_members"`
+	SuspendedMembers  int `json:"suspended_members"`
+	Sketches          int `json:"sketches"`
+	PublicSketches    int `json:"public_sketches"`
+	HiddenSketches    int `json:"hidden_sketches"`
+	SketchesThisWeek  int `json:"sketches_this_week"` // Created in the last 7 days
+	ActiveSessions    int `json:"active_sessions"`
+	OpenInviteCodes   int `json:"open_invite_codes"` // Not expired or used up
+}
+
+// ModerationSketch is a sketch as listed to moderators, whatever its visibility
+type ModerationSketch struct {
+	ID         int       `json:"id"`
+	MemberName string    `json:"member_name"`
+	Slug       string    `json:"slug"`
+	Title      string    `json:"title"`
+	Visibility string    `json:"visibility"`
+	Hidden     bool      `json:"hidden"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AdminUpdateMemberRequest holds the member fields moderators and admins can change.
+// Nil fields are left unchanged.
+type AdminUpdateMemberRequest struct {
+	Verified  *bool   `json:"verified,omitempty"`
+	Suspended *bool   `json:"suspended,omitempty"`
+	Role      *string `json:"role,omitempty" validate:"omitempty,oneof=member moderator admin"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// InviteCode lets new members register as verified members without waiting for approval
+type InviteCode struct {
+	Code       string     `json:"code"`
+	PresetName *string    `json:"preset_name,omitempty"` // If set, only this member name can use the code
+	MaxUses    int        `json:"max_uses"`
+	Uses       int        `json:"uses"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Never expires if nil
+	CreatedBy  *int       `json:"created_by,omitempty"` // Member who created the code (if any)
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateInviteCodeRequest represents the data needed to create an invite code
+type CreateInviteCodeRequest struct {
+	PresetName string     `json:"preset_name,omitempty"`
+	MaxUses    int        `json:"max_uses" validate:"min=1"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
@@ -10,12 +10,50 @@ type Member struct {
 	Name         string    `json:"name"`
 	PasswordHash string    `json:"password_hash"`
 	Verified     bool      `json:"verified"`
+	Role         string    `json:"role"`
+	Suspended    bool      `json:"suspended"`
+	Bio          string    `json:"bio"`
+	AvatarURL    string    `json:"avatar_url"`
+	Links        []string  `json:"links"`
+	LinksJSON    string    `json:"-"` // JSON string for database
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// Member roles, from least to most privileged
+const (
+	RoleMember    = "member"    // Can manage their own sketches and profile
+	RoleModerator = "moderator" // Can also approve and suspend members, and hide or delete any sketch
+	RoleAdmin     = "admin"     // Can also change roles and reset passwords
+)
+
+// roleRanks orders roles so that a higher role includes the powers of lower ones
+var roleRanks = map[string]int{
+	RoleMember:    1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// IsValidRole checks if a role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether the member has the given role or a more privileged one
+func (m *Member) HasRole(role string) bool {
+	return roleRanks[m.Role] >= roleRanks[role] && roleRanks[role] > 0
+}
+
 // CreateMemberRequest represents the data needed to create a new member
 type CreateMemberRequest struct {
 	Name         string `json:"name" validate:"required,min=1,max=50"`
 	PasswordHash string `json:"password_hash" validate:"required"`
 }
+
+// UpdateProfileRequest represents the public profile fields a member can edit
+type UpdateProfileRequest struct {
+	Bio       string   `json:"bio" validate:"max=500"`
+	AvatarURL string   `json:"avatar_url" validate:"omitempty,url"`
+	Links     []string `json:"links" validate:"max=5,dive,url"`
+}
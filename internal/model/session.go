@@ -1,13 +1,33 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 )
 
 // Session represents a member session
 type Session struct {
-	ID        string    `json:"id"`
-	MemberID  int       `json:"member_id"`
-	CreatedAt time.Time `json:"created_at"`
-	ExpiresAt time.Time `json:"expires_at"`
+	ID         string    `json:"id"`
+	MemberID   int       `json:"member_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Remember   bool      `json:"remember"` // Kept signed in for longer ("keep me signed in")
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// PublicID returns an identifier for the session that is safe to show in pages and URLs.
+// The session ID itself is a bearer credential and must only ever be sent in the session cookie.
+func (s *Session) PublicID() string {
+	sum := sha256.Sum256([]byte(s.ID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// CreateSessionRequest represents the data recorded when a member signs in
+type CreateSessionRequest struct {
+	UserAgent string
+	IP        string
+	Remember  bool
 }
@@ -14,44 +14,111 @@ type MemberSketchInfo struct {
 // It includes fields populated from file system context (Slug, URL, Alias)
 // and fields populated from sketch metadata JSON (Title, Description, etc.).
 type SketchInfo struct {
-	Slug  string `json:"-"`
-	URL   string `json:"-"` // URL to the sketch page
-	Alias string `json:"-"` // Member's alias
+	Slug  string `json:"slug"`
+	URL   string `json:"url"`   // URL to the sketch page
+	Alias string `json:"alias"` // Member's alias
 
 	// Fields from metadata JSON
 	Title       *string  `json:"title,omitempty"`
 	Description *string  `json:"description,omitempty"`
 	Keywords    *string  `json:"keywords,omitempty"`
 	Tags        []string `json:"tags,omitempty"`
+
+	// Fields set for search results only
+	Rank    float64 `json:"rank,omitempty"`
+	Snippet string  `json:"snippet,omitempty"` // HTML-escaped text with matches wrapped in <mark> tags
+}
+
+// Sort orders for sketch listings
+const (
+	SortUpdated = "updated" // Most recently updated first (default)
+	SortCreated = "created" // Most recently created first
+	SortTitle   = "title"   // Alphabetical by title
+)
+
+// ListSketchesOptions holds pagination, sorting and filtering options for sketch listings
+type ListSketchesOptions struct {
+	Sort        string // One of the Sort* constants, defaults to SortUpdated
+	Tag         string // Only sketches with this tag
+	ExternalLib string // Only sketches loading an external library whose URL contains this
+	Cursor      string // Opaque cursor returned with the previous page, empty for the first page
+	Limit       int    // Page size, defaults to 50 (at most 100)
+}
+
+// SearchSketchesRequest holds a full-text search query and its optional filters
+type SearchSketchesRequest struct {
+	Query             string     // Web search syntax: quoted phrases, OR, -excluded
+	MemberName        string     // Only sketches by this member
+	Tag               string     // Only sketches with this tag
+	ExternalLib       string     // Only sketches loading an external library whose URL contains this
+	From              *time.Time // Only sketches created on or after this time
+	To                *time.Time // Only sketches created before this time
+	IncludeSourceCode bool       // Also match against source code
+	Limit             int
+}
+
+// MaxSourceCodeLength is the most characters of source code a sketch can have,
+// as in the validate tags of CreateSketchRequest and UpdateSketchRequest
+const MaxSourceCodeLength = 1000000
+
+// Sketch visibility levels
+const (
+	VisibilityPublic   = "public"   // Listed everywhere
+	VisibilityUnlisted = "unlisted" // Viewable by anyone with a direct link, but not listed
+	VisibilityPrivate  = "private"  // Viewable by its owner only
+)
+
+// IsValidVisibility checks if a visibility value is one of the known levels
+func IsValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPublic, VisibilityUnlisted, VisibilityPrivate:
+		return true
+	}
+	return false
 }
 
 // Sketch represents a sketch stored in the database
 type Sketch struct {
-	ID               int       `json:"id" db:"id"`
-	MemberID         int       `json:"member_id" db:"member_id"`
-	Slug             string    `json:"slug" db:"slug"`
-	Title            string    `json:"title" db:"title"`
-	Description      string    `json:"description" db:"description"`
-	Keywords         string    `json:"keywords" db:"keywords"`
-	Tags             []string  `json:"tags" db:"-"`          // Will be stored as JSON
-	TagsJSON         string    `json:"-" db:"tags"`          // JSON string for database
-	ExternalLibs     []string  `json:"external_libs" db:"-"` // Will be stored as JSON
-	ExternalLibsJSON string    `json:"-" db:"external_libs"` // JSON string for database
-	SourceCode       string    `json:"source_code" db:"source_code"`
-	CreatedAt        time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+	ID                 int       `json:"id" db:"id"`
+	MemberID           int       `json:"member_id" db:"member_id"`
+	Slug               string    `json:"slug" db:"slug"`
+	Title              string    `json:"title" db:"title"`
+	Description        string    `json:"description" db:"description"`
+	Keywords           string    `json:"keywords" db:"keywords"`
+	Tags               []string  `json:"tags" db:"-"`          // Will be stored as JSON
+	TagsJSON           string    `json:"-" db:"tags"`          // JSON string for database
+	ExternalLibs       []string  `json:"external_libs" db:"-"` // Will be stored as JSON
+	ExternalLibsJSON   string    `json:"-" db:"external_libs"` // JSON string for database
+	SourceCode         string    `json:"source_code" db:"source_code"`
+	ForkedFromSketchID *int      `json:"forked_from_sketch_id" db:"forked_from_sketch_id"` // Sketch this one was remixed from (if any)
+	Visibility         string    `json:"visibility" db:"visibility"`
+	Hidden             bool      `json:"hidden" db:"hidden"`   // Hidden from everyone but its owner by a moderator
+	Version            int       `json:"version" db:"version"` // Incremented on every update
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// IsViewableBy reports whether a member can see the sketch via a direct link.
+// Use memberID 0 for anonymous visitors.
+func (s *Sketch) IsViewableBy(memberID int) bool {
+	if s.Visibility == VisibilityPrivate || s.Hidden {
+		return memberID > 0 && memberID == s.MemberID
+	}
+	return true
 }
 
 // CreateSketchRequest represents the data needed to create a new sketch
 type CreateSketchRequest struct {
-	Title        string     `json:"title" validate:"required,min=1,max=200"`
-	Description  string     `json:"description" validate:"max=1000"`
-	Keywords     string     `json:"keywords" validate:"max=500"`
-	Tags         []string   `json:"tags" validate:"dive,min=1,max=50"`
-	ExternalLibs []string   `json:"external_libs" validate:"dive,min=1,max=100"`
-	SourceCode   string     `json:"source_code" validate:"required,min=1,max=1000000"` // 1MB max for UTF-8
-	CreatedAt    *time.Time `json:"created_at,omitempty"`                              // Optional
-	UpdatedAt    *time.Time `json:"updated_at,omitempty"`                              // Optional
+	Title              string     `json:"title" validate:"required,min=1,max=200"`
+	Description        string     `json:"description" validate:"max=1000"`
+	Keywords           string     `json:"keywords" validate:"max=500"`
+	Tags               []string   `json:"tags" validate:"dive,min=1,max=50"`
+	ExternalLibs       []string   `json:"external_libs" validate:"dive,min=1,max=100"`
+	SourceCode         string     `json:"source_code" validate:"required,min=1,max=1000000"` // 1MB max for UTF-8
+	ForkedFromSketchID *int       `json:"forked_from_sketch_id,omitempty"`                   // Optional
+	Visibility         string     `json:"visibility,omitempty"`                              // Optional, defaults to public
+	CreatedAt          *time.Time `json:"created_at,omitempty"`                              // Optional
+	UpdatedAt          *time.Time `json:"updated_at,omitempty"`                              // Optional
 }
 
 // UpdateSketchRequest represents the data that can be updated for an existing sketch
@@ -62,4 +129,17 @@ type UpdateSketchRequest struct {
 	Tags         []string `json:"tags,omitempty" validate:"dive,min=1,max=50"`
 	ExternalLibs []string `json:"external_libs,omitempty" validate:"dive,min=1,max=100"`
 	SourceCode   *string  `json:"source_code,omitempty" validate:"omitempty,min=1,max=1000000"` // 1MB max for UTF-8
+	Visibility   *string  `json:"visibility,omitempty" validate:"omitempty,oneof=public unlisted private"`
+
+	// Only update the sketch if it is still at this version (optional)
+	ExpectedVersion *int `json:"-"`
+}
+
+// SketchRevision represents an immutable snapshot of a sketch's source code
+type SketchRevision struct {
+	ID             int       `json:"id" db:"id"`
+	SketchID       int       `json:"sketch_id" db:"sketch_id"`
+	RevisionNumber int       `json:"revision" db:"revision_number"`
+	SourceCode     string    `json:"source_code,omitempty" db:"source_code"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 }
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/services"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// Admin API handlers
+// These are only routed through requireRole, so the authenticated member is at least a moderator
+
+// AdminMemberResponse represents a member as shown to moderators and admins
+type AdminMemberResponse struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	Verified  bool   `json:"verified"`
+	Suspended bool   `json:"suspended"`
+	CreatedAt string `json:"created_at"`
+}
+
+// HideSketchRequest represents the request body for hiding or unhiding a sketch
+type HideSketchRequest struct {
+	Hidden bool `json:"hidden"`
+}
+
+// getModeratorAndTarget returns the authenticated member and the member named in the {memberName} path variable.
+// Moderators can only manage regular members; admins can manage everyone but themselves.
+// It writes a JSON error response and returns false if the action is not allowed.
+func getModeratorAndTarget(w http.ResponseWriter, r *http.Request, services *services.Services) (*model.Member, *model.Member, bool) {
+	memberID, ok := r.Context().Value("authenticated_member_id").(int)
+	if !ok {
+		http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	moderator, err := services.Member.GetMemberByID(memberID)
+	if err != nil {
+		http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	target, err := services.Member.GetMemberByName(utils.PathVariable(r, "memberName"))
+	if err != nil {
+		http.Error(w, `{"error":"Member not found"}`, http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	if target.ID == moderator.ID {
+		http.Error(w, `{"error":"You cannot change your own account here"}`, http.StatusForbidden)
+		return nil, nil, false
+	}
+	if target.HasRole(model.RoleModerator) && !moderator.HasRole(model.RoleAdmin) {
+		http.Error(w, `{"error":"Only admins can manage moderators and admins"}`, http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	return moderator, target, true
+}
+
+// GetAdminStatsHandler handles GET requests to return the admin dashboard counts
+func GetAdminStatsHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		stats, err := services.Admin.GetStats()
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error getting admin stats: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding admin stats: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// AdminUpdateMemberHandler handles PATCH requests to verify, suspend or change the role of a member.
+// Changing roles is reserved to admins. Suspending a member also signs them out everywhere.
+func AdminUpdateMemberHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		moderator, target, ok := getModeratorAndTarget(w, r, services)
+		if !ok {
+			return
+		}
+
+		var req model.AdminUpdateMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.LogRequestErrorf(r, "Error decoding admin member update request: %v", err)
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		if req.Verified != nil && !*req.Verified {
+			http.Error(w, `{"error":"Approved members cannot be moved back to the approval queue"}`, http.StatusBadRequest)
+			return
+		}
+		if req.Role != nil {
+			if !moderator.HasRole(model.RoleAdmin) {
+				http.Error(w, `{"error":"Only admins can change roles"}`, http.StatusForbidden)
+				return
+			}
+			if !model.IsValidRole(*req.Role) {
+				http.Error(w, `{"error":"Role must be member, moderator or admin"}`, http.StatusBadRequest)
+				return
+			}
+		}
+
+		if req.Verified != nil && !target.Verified {
+			if err := services.Member.VerifyMember(target.ID); err != nil {
+				utils.LogRequestErrorf(r, "Error verifying member %d: %v", target.ID, err)
+				http.Error(w, `{"error":"Failed to update member"}`, http.StatusInternalServerError)
+				return
+			}
+			log.Printf("Member %s approved by %s", target.Name, moderator.Name)
+		}
+
+		if req.Suspended != nil && *req.Suspended != target.Suspended {
+			if err := services.Member.SetSuspended(target.ID, *req.Suspended); err != nil {
+				utils.LogRequestErrorf(r, "Error setting suspended=%t for member %d: %v", *req.Suspended, target.ID, err)
+				http.Error(w, `{"error":"Failed to update member"}`, http.StatusInternalServerError)
+				return
+			}
+			if *req.Suspended {
+				if err := services.Session.DeleteMemberSessions(target.ID); err != nil {
+					utils.LogRequestErrorf(r, "Error signing out suspended member %d: %v", target.ID, err)
+				}
+			}
+			log.Printf("Member %s suspended=%t by %s", target.Name, *req.Suspended, moderator.Name)
+		}
+
+		if req.Role != nil && *req.Role != target.Role {
+			if err := services.Member.SetRole(target.ID, *req.Role); err != nil {
+				utils.LogRequestErrorf(r, "Error setting role for member %d: %v", target.ID, err)
+				http.Error(w, `{"error":"Failed to update member"}`, http.StatusInternalServerError)
+				return
+			}
+			log.Printf("Member %s role changed from %s to %s by %s", target.Name, target.Role, *req.Role, moderator.Name)
+		}
+
+		updated, err := services.Member.GetMemberByID(target.ID)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error getting updated member %d: %v", target.ID, err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		response := AdminMemberResponse{
+			ID:        updated.ID,
+			Name:      updated.Name,
+			Role:      updated.Role,
+			Verified:  updated.Verified,
+			Suspended: updated.Suspended,
+			CreatedAt: updated.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding admin member response: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// AdminResetPasswordHandler handles POST requests to replace a member's password with a temporary one.
+// The temporary password is returned once so that the admin can pass it on; the member is signed out everywhere.
+func AdminResetPasswordHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		admin, target, ok := getModeratorAndTarget(w, r, services)
+		if !ok {
+			return
+		}
+
+		password, err := utils.GenerateTemporaryPassword()
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error generating temporary password: %v", err)
+			http.Error(w, `{"error":"Failed to reset password"}`, http.StatusInternalServerError)
+			return
+		}
+		passwordHash, err := utils.HashPassword(password)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error hashing temporary password: %v", err)
+			http.Error(w, `{"error":"Failed to reset password"}`, http.StatusInternalServerError)
+			return
+		}
+
+		if err := services.Member.UpdatePasswordHash(target.ID, passwordHash); err != nil {
+			utils.LogRequestErrorf(r, "Error resetting password for member %d: %v", target.ID, err)
+			http.Error(w, `{"error":"Failed to reset password"}`, http.StatusInternalServerError)
+			return
+		}
+		if err := services.Session.DeleteMemberSessions(target.ID); err != nil {
+			utils.LogRequestErrorf(r, "Error signing out member %d after password reset: %v", target.ID, err)
+		}
+
+		log.Printf("Password for member %s reset by %s", target.Name, admin.Name)
+
+		json.NewEncoder(w).Encode(map[string]string{"temporary_password": password})
+	}
+}
+
+// getSketchForModeration resolves the {memberName}/{sketchSlug} path variables to a stored sketch,
+// whatever its visibility. It writes a JSON error response and returns false if the sketch cannot be found.
+func getSketchForModeration(w http.ResponseWriter, r *http.Request, services *services.Services) (*model.Sketch, bool) {
+	memberName := utils.PathVariable(r, "memberName")
+	sketchSlug := utils.PathVariable(r, "sketchSlug")
+
+	member, err := services.Member.GetMemberByName(memberName)
+	if err != nil {
+		http.Error(w, `{"error":"Sketch not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+
+	sketch, err := services.Sketch.GetSketchByMemberAndSlug(member.ID, sketchSlug)
+	if err != nil || sketch.ID == 0 {
+		http.Error(w, `{"error":"Sketch not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+
+	return sketch, true
+}
+
+// AdminHideSketchHandler handles PATCH requests to hide a sketch from everyone but its owner, or unhide it
+func AdminHideSketchHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		sketch, ok := getSketchForModeration(w, r, services)
+		if !ok {
+			return
+		}
+
+		var req HideSketchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.LogRequestErrorf(r, "Error decoding hide sketch request: %v", err)
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		if err := services.Sketch.SetSketchHidden(sketch.ID, req.Hidden); err != nil {
+			utils.LogRequestErrorf(r, "Error setting hidden=%t for sketch %d: %v", req.Hidden, sketch.ID, err)
+			http.Error(w, `{"error":"Failed to update sketch"}`, http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Sketch %d hidden=%t by member %v", sketch.ID, req.Hidden, r.Context().Value("authenticated_member_id"))
+
+		w.Write([]byte(fmt.Sprintf(`{"success": true, "hidden": %t}`, req.Hidden)))
+	}
+}
+
+// AdminDeleteSketchHandler handles DELETE requests to delete any member's sketch
+func AdminDeleteSketchHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		sketch, ok := getSketchForModeration(w, r, services)
+		if !ok {
+			return
+		}
+
+		if err := services.Sketch.DeleteSketch(sketch.ID); err != nil {
+			utils.LogRequestErrorf(r, "Error deleting sketch %d: %v", sketch.ID, err)
+			http.Error(w, `{"error":"Failed to delete sketch"}`, http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Sketch %d deleted by member %v", sketch.ID, r.Context().Value("authenticated_member_id"))
+
+		w.Write([]byte(`{"success": true, "message": "Sketch deleted successfully"}`))
+	}
+}
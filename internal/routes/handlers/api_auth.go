@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 
 	"github.com/sb-luis/creative-coding-bookclub/internal/services"
@@ -21,7 +20,7 @@ func SignOutHandler(services *services.Services) http.HandlerFunc {
 			// Delete the session from database
 			if services != nil {
 				if err := services.Session.DeleteSession(sessionID); err != nil {
-					log.Printf("Error signing out member: %v", err)
+					utils.LogRequestErrorf(r, "Error signing out member: %v", err)
 				}
 			}
 		}
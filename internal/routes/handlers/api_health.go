@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/metrics"
+	"github.com/sb-luis/creative-coding-bookclub/internal/services"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// Health and monitoring handlers
+// These are meant for container probes and a Prometheus scraper rather than for browsers
+
+// readinessTimeout bounds the database checks of the readiness probe
+const readinessTimeout = 2 * time.Second
+
+// HealthzHandler handles GET requests to check that the process is up and serving requests
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// ReadyzHandler handles GET requests to check that the server can handle traffic:
+// the database answers and all migrations have been applied
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	db := utils.GetDB()
+	if db == nil {
+		http.Error(w, `{"status":"unavailable","error":"Database not initialized"}`, http.StatusServiceUnavailable)
+		return
+	}
+	if err := db.PingContext(ctx); err != nil {
+		utils.LogRequestErrorf(r, "Readiness check failed, database ping: %v", err)
+		http.Error(w, `{"status":"unavailable","error":"Database unreachable"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	pending, err := utils.CountPendingMigrations(ctx, db)
+	if err != nil {
+		utils.LogRequestErrorf(r, "Readiness check failed, migrations: %v", err)
+		http.Error(w, `{"status":"unavailable","error":"Could not check migrations"}`, http.StatusServiceUnavailable)
+		return
+	}
+	if pending > 0 {
+		http.Error(w, `{"status":"unavailable","error":"Migrations pending"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// MetricsHandler handles GET requests to return metrics in the Prometheus text format.
+// When METRICS_TOKEN is set, requests must send it as a bearer token.
+func MetricsHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := os.Getenv("METRICS_TOKEN"); token != "" {
+			expected := []byte("Bearer " + token)
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		gauges := metrics.Gauges{}
+		if db := utils.GetDB(); db != nil {
+			gauges.DB = db.Stats()
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+		activeSessions, err := services.Session.CountActiveSessions(ctx)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error counting active sessions for metrics: %v", err)
+		}
+		gauges.ActiveSessions = activeSessions
+
+		// Render to a buffer first, so that the response is never cut short
+		var body bytes.Buffer
+		metrics.Write(&body, gauges)
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(body.Bytes())
+	}
+}
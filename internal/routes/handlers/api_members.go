@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services"
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
 )
@@ -30,7 +34,7 @@ func GetMembersHandler(services *services.Services) http.HandlerFunc {
 		// Get all members from service
 		members, err := services.Member.GetAllMembers()
 		if err != nil {
-			log.Printf("Error getting all members: %v", err)
+			utils.LogRequestErrorf(r, "Error getting all members: %v", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
@@ -46,7 +50,7 @@ func GetMembersHandler(services *services.Services) http.HandlerFunc {
 
 		// Return JSON response
 		if err := json.NewEncoder(w).Encode(memberResponses); err != nil {
-			log.Printf("Error encoding JSON response for members: %v", err)
+			utils.LogRequestErrorf(r, "Error encoding JSON response for members: %v", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
@@ -71,7 +75,7 @@ func GetCurrentMemberHandler(services *services.Services) http.HandlerFunc {
 		// Get member details
 		member, err := services.Member.GetMemberByID(memberID)
 		if err != nil {
-			log.Printf("Error getting member by ID %d: %v", memberID, err)
+			utils.LogRequestErrorf(r, "Error getting member by ID %d: %v", memberID, err)
 			http.Error(w, `{"error":"Member not found"}`, http.StatusNotFound)
 			return
 		}
@@ -83,7 +87,7 @@ func GetCurrentMemberHandler(services *services.Services) http.HandlerFunc {
 		}
 
 		if err := json.NewEncoder(w).Encode(response); err != nil {
-			log.Printf("Error encoding current member response: %v", err)
+			utils.LogRequestErrorf(r, "Error encoding current member response: %v", err)
 			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 			return
 		}
@@ -115,7 +119,7 @@ func UpdatePasswordHandler(services *services.Services) http.HandlerFunc {
 		// Parse request body
 		var req UpdatePasswordRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Printf("Error decoding password update request: %v", err)
+			utils.LogRequestErrorf(r, "Error decoding password update request: %v", err)
 			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
 			return
 		}
@@ -136,14 +140,18 @@ func UpdatePasswordHandler(services *services.Services) http.HandlerFunc {
 			return
 		}
 
-		// Hash passwords
-		currentPasswordHash := utils.HashPassword(req.CurrentPassword)
-		newPasswordHash := utils.HashPassword(req.NewPassword)
+		// Hash the new password
+		newPasswordHash, err := utils.HashPassword(req.NewPassword)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error hashing new password for member ID %d: %v", memberID, err)
+			http.Error(w, `{"error":"Failed to update password. Please try again."}`, http.StatusInternalServerError)
+			return
+		}
 
-		// Update password using the service
-		err := services.Member.UpdatePassword(memberID, currentPasswordHash, newPasswordHash)
+		// Update password using the service (verifies the current password)
+		err = services.Member.UpdatePassword(memberID, req.CurrentPassword, newPasswordHash)
 		if err != nil {
-			log.Printf("Error updating password for member ID %d: %v", memberID, err)
+			utils.LogRequestErrorf(r, "Error updating password for member ID %d: %v", memberID, err)
 
 			// Return appropriate error messages
 			switch err.Error() {
@@ -164,3 +172,284 @@ func UpdatePasswordHandler(services *services.Services) http.HandlerFunc {
 		log.Printf("Successfully updated password for member ID %d", memberID)
 	}
 }
+
+// Profile validation constants
+const (
+	MaxBioLength     = 500
+	MaxProfileLinks  = 5
+	MaxProfileURLLen = 300
+)
+
+// ProfileResponse represents a member's public profile in API responses
+type ProfileResponse struct {
+	Name      string   `json:"name"`
+	Bio       string   `json:"bio"`
+	AvatarURL string   `json:"avatar_url"`
+	Links     []string `json:"links"`
+	JoinedAt  string   `json:"joined_at"`
+}
+
+// validateProfileURL checks that a profile URL is an absolute http(s) URL
+func validateProfileURL(rawURL string, httpsOnly bool) error {
+	if len(rawURL) > MaxProfileURLLen {
+		return fmt.Errorf("URLs must be %d characters or less", MaxProfileURLLen)
+	}
+	parsed, err := url.ParseRequestURI(rawURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("invalid URL format: %s", rawURL)
+	}
+	if httpsOnly && parsed.Scheme != "https" {
+		return fmt.Errorf("URL must use HTTPS: %s", rawURL)
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return fmt.Errorf("URL must use HTTP or HTTPS: %s", rawURL)
+	}
+	return nil
+}
+
+// validateProfile checks and normalizes a profile update request
+func validateProfile(req *model.UpdateProfileRequest) error {
+	req.Bio = strings.TrimSpace(req.Bio)
+	if len(req.Bio) > MaxBioLength {
+		return fmt.Errorf("bio must be %d characters or less", MaxBioLength)
+	}
+
+	req.AvatarURL = strings.TrimSpace(req.AvatarURL)
+	if req.AvatarURL != "" {
+		if err := validateProfileURL(req.AvatarURL, true); err != nil {
+			return fmt.Errorf("avatar: %w", err)
+		}
+	}
+
+	var links []string
+	for _, link := range req.Links {
+		link = strings.TrimSpace(link)
+		if link == "" {
+			continue
+		}
+		if err := validateProfileURL(link, false); err != nil {
+			return err
+		}
+		links = append(links, link)
+	}
+	if len(links) > MaxProfileLinks {
+		return fmt.Errorf("maximum %d links allowed", MaxProfileLinks)
+	}
+	req.Links = links
+
+	return nil
+}
+
+// UpdateProfileHandler handles PUT requests to replace the authenticated member's public profile
+func UpdateProfileHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		// Get authenticated member ID from context
+		memberID, ok := r.Context().Value("authenticated_member_id").(int)
+		if !ok {
+			http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+			return
+		}
+
+		// Parse request body
+		var req model.UpdateProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.LogRequestErrorf(r, "Error decoding profile update request: %v", err)
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		if err := validateProfile(&req); err != nil {
+			errorJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+			http.Error(w, string(errorJSON), http.StatusBadRequest)
+			return
+		}
+
+		member, err := services.Member.UpdateProfile(memberID, &req)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error updating profile for member ID %d: %v", memberID, err)
+			http.Error(w, `{"error":"Failed to update profile. Please try again."}`, http.StatusInternalServerError)
+			return
+		}
+
+		response := ProfileResponse{
+			Name:      member.Name,
+			Bio:       member.Bio,
+			AvatarURL: member.AvatarURL,
+			Links:     member.Links,
+			JoinedAt:  member.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding profile response: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Updated profile for member ID %d", memberID)
+	}
+}
+
+// SessionResponse represents one of the authenticated member's sessions in API responses
+type SessionResponse struct {
+	ID         string `json:"id"` // Public ID, not the session cookie value
+	Device     string `json:"device"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	Current    bool   `json:"current"` // The session making the request
+	Remember   bool   `json:"remember"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+}
+
+// describeUserAgent returns a short description of a browser user agent, e.g. "Firefox on Linux"
+func describeUserAgent(userAgent string) string {
+	browser := "Unknown browser"
+	for _, candidate := range []struct{ token, name string }{
+		// Order matters: Edge and Opera user agents also mention Chrome, and Chrome's mentions Safari
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	system := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			system = candidate.name
+			break
+		}
+	}
+
+	if system == "" {
+		return browser
+	}
+	return browser + " on " + system
+}
+
+// newSessionResponses converts sessions for API responses and pages, flagging the current one
+func newSessionResponses(sessions []*model.Session, currentSessionID string) []SessionResponse {
+	responses := []SessionResponse{}
+	for _, session := range sessions {
+		responses = append(responses, SessionResponse{
+			ID:         session.PublicID(),
+			Device:     describeUserAgent(session.UserAgent),
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			Current:    session.ID == currentSessionID,
+			Remember:   session.Remember,
+			CreatedAt:  session.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			LastSeenAt: session.LastSeenAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+	return responses
+}
+
+// GetSessionsHandler handles GET requests to list the authenticated member's active sessions
+func GetSessionsHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		// Get authenticated member ID from context
+		memberID, ok := r.Context().Value("authenticated_member_id").(int)
+		if !ok {
+			http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+			return
+		}
+
+		sessions, err := services.Session.GetMemberSessions(memberID)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error getting sessions for member ID %d: %v", memberID, err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		currentSessionID, _ := utils.GetSessionFromRequest(r)
+		if err := json.NewEncoder(w).Encode(newSessionResponses(sessions, currentSessionID)); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding sessions response: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// DeleteSessionHandler handles DELETE requests to sign the authenticated member out of one session
+func DeleteSessionHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		// Get authenticated member ID from context
+		memberID, ok := r.Context().Value("authenticated_member_id").(int)
+		if !ok {
+			http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+			return
+		}
+
+		publicID := utils.PathVariable(r, "sessionID")
+		if err := services.Session.DeleteMemberSession(memberID, publicID); err != nil {
+			if err.Error() == "session not found" {
+				http.Error(w, `{"error":"Session not found"}`, http.StatusNotFound)
+				return
+			}
+			utils.LogRequestErrorf(r, "Error deleting session for member ID %d: %v", memberID, err)
+			http.Error(w, `{"error":"Failed to sign out session"}`, http.StatusInternalServerError)
+			return
+		}
+
+		// Signing out the current device also clears its cookie
+		current := false
+		if sessionID, err := utils.GetSessionFromRequest(r); err == nil {
+			current = (&model.Session{ID: sessionID}).PublicID() == publicID
+		}
+		if current {
+			utils.ClearSessionCookie(w)
+		}
+
+		log.Printf("Member ID %d signed out a session", memberID)
+
+		json.NewEncoder(w).Encode(map[string]bool{"success": true, "current": current})
+	}
+}
+
+// DeleteAllSessionsHandler handles DELETE requests to sign the authenticated member out everywhere
+func DeleteAllSessionsHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		// Get authenticated member ID from context
+		memberID, ok := r.Context().Value("authenticated_member_id").(int)
+		if !ok {
+			http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+			return
+		}
+
+		if err := services.Session.DeleteMemberSessions(memberID); err != nil {
+			utils.LogRequestErrorf(r, "Error deleting all sessions for member ID %d: %v", memberID, err)
+			http.Error(w, `{"error":"Failed to sign out everywhere"}`, http.StatusInternalServerError)
+			return
+		}
+		utils.ClearSessionCookie(w)
+
+		log.Printf("Member ID %d signed out everywhere", memberID)
+
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	}
+}
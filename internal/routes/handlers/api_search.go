@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/services"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// parseSearchRequest builds a search request from query parameters:
+// q, member, tag, lib, from and to (YYYY-MM-DD, both inclusive), code (1 or true) and limit.
+// It returns an error message suitable for the client if a parameter is invalid.
+func parseSearchRequest(query url.Values) (*model.SearchSketchesRequest, string) {
+	req := &model.SearchSketchesRequest{
+		Query:       strings.TrimSpace(query.Get("q")),
+		MemberName:  query.Get("member"),
+		Tag:         query.Get("tag"),
+		ExternalLib: query.Get("lib"),
+	}
+
+	if from := query.Get("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, "from must be a date in YYYY-MM-DD format"
+		}
+		req.From = &date
+	}
+	if to := query.Get("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, "to must be a date in YYYY-MM-DD format"
+		}
+		// Include the whole "to" day
+		date = date.AddDate(0, 0, 1)
+		req.To = &date
+	}
+
+	if code := query.Get("code"); code != "" {
+		includeSourceCode, err := strconv.ParseBool(code)
+		if err != nil {
+			return nil, "code must be true or false"
+		}
+		req.IncludeSourceCode = includeSourceCode
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, "limit must be a positive number"
+		}
+		req.Limit = n
+	}
+
+	return req, ""
+}
+
+// SearchSketchesHandler handles GET requests to search public sketches by text
+func SearchSketchesHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		req, errMessage := parseSearchRequest(r.URL.Query())
+		if errMessage != "" {
+			http.Error(w, `{"error":"`+errMessage+`"}`, http.StatusBadRequest)
+			return
+		}
+		if req.Query == "" {
+			http.Error(w, `{"error":"Search query is required"}`, http.StatusBadRequest)
+			return
+		}
+
+		results, err := services.Sketch.SearchSketches(req)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error searching sketches for %q: %v", req.Query, err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		// Always return a JSON array, even without results
+		if results == nil {
+			results = []model.SketchInfo{}
+		}
+
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding search results: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Served %d search results for %q", len(results), req.Query)
+	}
+}
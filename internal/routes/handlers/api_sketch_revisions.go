@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/metrics"
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/services"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// SketchRevisionResponse represents a sketch revision in API responses
+type SketchRevisionResponse struct {
+	Revision   int    `json:"revision"`
+	SourceCode string `json:"source_code,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// getSketchFromPath resolves the {memberName}/{sketchSlug} path variables to a stored sketch.
+// It writes a JSON error response and returns false if the sketch cannot be found.
+func getSketchFromPath(w http.ResponseWriter, r *http.Request, services *services.Services) (*model.Sketch, bool) {
+	memberName := utils.PathVariable(r, "memberName")
+	sketchSlug := utils.PathVariable(r, "sketchSlug")
+
+	if memberName == "" || sketchSlug == "" {
+		log.Printf("Invalid request: missing member name or sketch slug")
+		http.Error(w, `{"error":"Member name and sketch slug are required"}`, http.StatusBadRequest)
+		return nil, false
+	}
+
+	if services == nil {
+		log.Printf("Services not initialized")
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	member, err := services.Member.GetMemberByName(memberName)
+	if err != nil {
+		log.Printf("Member not found: %s", memberName)
+		http.Error(w, `{"error":"Sketch not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+
+	sketch, err := services.Sketch.GetSketchByMemberAndSlug(member.ID, sketchSlug)
+	if err != nil || sketch.ID == 0 {
+		log.Printf("Sketch not found: %s by member %s", sketchSlug, memberName)
+		http.Error(w, `{"error":"Sketch not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+
+	// Private and hidden sketches are reported as missing to everyone but their owner
+	if !sketch.IsViewableBy(viewerMemberID(r, services)) {
+		log.Printf("Sketch %s by member %s is private or hidden", sketchSlug, memberName)
+		http.Error(w, `{"error":"Sketch not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+
+	return sketch, true
+}
+
+// viewerMemberID returns the ID of the member making the request, or 0 for anonymous visitors.
+// It uses the ID set by authMiddleware when present and falls back to the session cookie.
+func viewerMemberID(r *http.Request, services *services.Services) int {
+	if memberID, ok := r.Context().Value("authenticated_member_id").(int); ok {
+		return memberID
+	}
+
+	sessionID, err := utils.GetSessionFromRequest(r)
+	if err != nil {
+		return 0
+	}
+	memberID, err := services.Session.GetMemberIDFromSession(sessionID)
+	if err != nil {
+		return 0
+	}
+	return memberID
+}
+
+// parseRevisionNumber parses a positive revision number from a path variable or query value
+func parseRevisionNumber(value string) (int, bool) {
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision <= 0 {
+		return 0, false
+	}
+	return revision, true
+}
+
+// GetSketchRevisionsHandler handles GET requests to list the revisions of a sketch (without source code)
+func GetSketchRevisionsHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		sketch, ok := getSketchFromPath(w, r, services)
+		if !ok {
+			return
+		}
+
+		revisions, err := services.Sketch.GetRevisions(sketch.ID)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error getting revisions for sketch %d: %v", sketch.ID, err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		revisionResponses := []SketchRevisionResponse{}
+		for _, revision := range revisions {
+			revisionResponses = append(revisionResponses, SketchRevisionResponse{
+				Revision:  revision.RevisionNumber,
+				CreatedAt: revision.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			})
+		}
+
+		if err := json.NewEncoder(w).Encode(revisionResponses); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding JSON response for sketch revisions: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Served %d revisions for sketch %d via API", len(revisionResponses), sketch.ID)
+	}
+}
+
+// GetSketchRevisionHandler handles GET requests to return a single revision including its source code
+func GetSketchRevisionHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		revisionNumber, ok := parseRevisionNumber(utils.PathVariable(r, "revision"))
+		if !ok {
+			http.Error(w, `{"error":"Invalid revision number"}`, http.StatusBadRequest)
+			return
+		}
+
+		sketch, ok := getSketchFromPath(w, r, services)
+		if !ok {
+			return
+		}
+
+		revision, err := services.Sketch.GetRevision(sketch.ID, revisionNumber)
+		if err != nil {
+			log.Printf("Revision %d not found for sketch %d: %v", revisionNumber, sketch.ID, err)
+			http.Error(w, `{"error":"Revision not found"}`, http.StatusNotFound)
+			return
+		}
+
+		response := SketchRevisionResponse{
+			Revision:   revision.RevisionNumber,
+			SourceCode: revision.SourceCode,
+			CreatedAt:  revision.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding JSON response for sketch revision: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetSketchRevisionDiffHandler handles GET requests to return a unified diff between two revisions.
+// The revisions are given by the ?from= and ?to= query parameters.
+func GetSketchRevisionDiffHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON errors, overridden on success
+		w.Header().Set("Content-Type", "application/json")
+
+		fromRevision, okFrom := parseRevisionNumber(r.URL.Query().Get("from"))
+		toRevision, okTo := parseRevisionNumber(r.URL.Query().Get("to"))
+		if !okFrom || !okTo {
+			http.Error(w, `{"error":"Query parameters 'from' and 'to' must be revision numbers"}`, http.StatusBadRequest)
+			return
+		}
+
+		sketch, ok := getSketchFromPath(w, r, services)
+		if !ok {
+			return
+		}
+
+		diff, err := services.Sketch.DiffRevisions(sketch.ID, fromRevision, toRevision)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error diffing revisions %d..%d of sketch %d: %v", fromRevision, toRevision, sketch.ID, err)
+			if err.Error() == "revision not found" {
+				http.Error(w, `{"error":"Revision not found"}`, http.StatusNotFound)
+			} else if err.Error() == "sketch not found" {
+				http.Error(w, `{"error":"Sketch not found"}`, http.StatusNotFound)
+			} else {
+				http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+		if _, err := w.Write([]byte(diff)); err != nil {
+			utils.LogRequestErrorf(r, "Error writing diff response for sketch %d: %v", sketch.ID, err)
+		}
+	}
+}
+
+// RestoreSketchRevisionHandler handles POST requests to make an old revision the current source code
+func RestoreSketchRevisionHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		// Get authenticated member ID from context (set by authMiddleware)
+		memberID, ok := r.Context().Value("authenticated_member_id").(int)
+		if !ok {
+			http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+			return
+		}
+
+		revisionNumber, ok := parseRevisionNumber(utils.PathVariable(r, "revision"))
+		if !ok {
+			http.Error(w, `{"error":"Invalid revision number"}`, http.StatusBadRequest)
+			return
+		}
+
+		sketch, ok := getSketchFromPath(w, r, services)
+		if !ok {
+			return
+		}
+
+		// Verify the authenticated user is restoring their own sketch
+		if sketch.MemberID != memberID {
+			http.Error(w, `{"error":"You can only restore your own sketches"}`, http.StatusForbidden)
+			return
+		}
+
+		restoredSketch, err := services.Sketch.RestoreRevision(sketch.ID, revisionNumber)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error restoring revision %d of sketch %d: %v", revisionNumber, sketch.ID, err)
+			if err.Error() == "revision not found" {
+				http.Error(w, `{"error":"Revision not found"}`, http.StatusNotFound)
+			} else if err.Error() == "sketch not found" {
+				http.Error(w, `{"error":"Sketch not found"}`, http.StatusNotFound)
+			} else {
+				http.Error(w, `{"error":"Failed to restore revision"}`, http.StatusInternalServerError)
+			}
+			return
+		}
+		metrics.IncSketchSaves("restore")
+
+		w.Header().Set("ETag", sketchETag(restoredSketch))
+		if err := json.NewEncoder(w).Encode(restoredSketch); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding restored sketch response: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Restored revision %d of sketch %d for member %d", revisionNumber, sketch.ID, memberID)
+	}
+}
@@ -1,15 +1,21 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/sb-luis/creative-coding-bookclub/internal/jscode"
+	"github.com/sb-luis/creative-coding-bookclub/internal/metrics"
 	"github.com/sb-luis/creative-coding-bookclub/internal/model"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services"
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
@@ -136,6 +142,50 @@ func validateExternalLibs(libs []string) error {
 	return nil
 }
 
+// parseListOptions reads pagination, sorting and filtering options from query parameters:
+// limit, cursor, sort (created, updated or title), tag and lib.
+// Sort and cursor are validated by the sketch service.
+func parseListOptions(query url.Values) (*model.ListSketchesOptions, error) {
+	opts := &model.ListSketchesOptions{
+		Sort:        query.Get("sort"),
+		Tag:         query.Get("tag"),
+		ExternalLib: query.Get("lib"),
+		Cursor:      query.Get("cursor"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("limit must be a positive number")
+		}
+		opts.Limit = n
+	}
+	return opts, nil
+}
+
+// isListOptionsError reports whether a sketch service error was caused by invalid listing options
+func isListOptionsError(err error) bool {
+	return err.Error() == "invalid sort" || err.Error() == "invalid cursor"
+}
+
+// nextPageURL returns the URL of the current request with its cursor replaced by nextCursor,
+// or an empty string if there is no next page
+func nextPageURL(r *http.Request, nextCursor string) string {
+	if nextCursor == "" {
+		return ""
+	}
+	query := r.URL.Query()
+	query.Set("cursor", nextCursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return next.String()
+}
+
+// setNextPageLink sets a Link header pointing to the next page of results (if any)
+func setNextPageLink(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	if next := nextPageURL(r, nextCursor); next != "" {
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next))
+	}
+}
+
 // Request structs for sketch endpoints
 
 // SketchCreateRequest represents the payload for creating a new sketch (only source code)
@@ -155,6 +205,7 @@ type SketchMetadataUpdateRequest struct {
 	Keywords     string   `json:"keywords,omitempty"`
 	Tags         []string `json:"tags,omitempty"`
 	ExternalLibs []string `json:"external_libs,omitempty"`
+	Visibility   string   `json:"visibility,omitempty"` // Unchanged if omitted
 }
 
 // Response structs
@@ -168,13 +219,75 @@ type SketchResponse struct {
 	Keywords     string   `json:"keywords"`
 	Tags         []string `json:"tags"`
 	ExternalLibs []string `json:"external_libs"`
+	Visibility   string   `json:"visibility"`
+	Version      int      `json:"version"`
 	CreatedAt    string   `json:"created_at"`
 	UpdatedAt    string   `json:"updated_at"`
 }
 
+// SketchSaveResponse is a saved sketch with the problems found in its source code
+type SketchSaveResponse struct {
+	*model.Sketch
+	Diagnostics []jscode.Diagnostic `json:"diagnostics"`
+}
+
+// SketchSyntaxErrorResponse is returned when strict mode refuses to save code with syntax errors
+type SketchSyntaxErrorResponse struct {
+	Error       string              `json:"error"`
+	Diagnostics []jscode.Diagnostic `json:"diagnostics"`
+}
+
+// SketchConflictResponse is returned when a sketch was saved again since the version a write was
+// based on, with the current sketch so that the client can offer to merge or overwrite
+type SketchConflictResponse struct {
+	Error   string        `json:"error"`
+	Current *model.Sketch `json:"current"`
+}
+
 // PUBLIC ENDPOINTS (NO AUTH REQUIRED)
 
-// GetMemberSketchesHandler handles GET requests to return all sketches for a specific member
+// GetSketchesHandler handles GET requests to return a page of public sketches from all members.
+// The next page (if any) is linked from the Link header.
+func GetSketchesHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		opts, err := parseListOptions(r.URL.Query())
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		sketches, nextCursor, err := services.Sketch.GetAllSketchesChronological(opts)
+		if err != nil {
+			if isListOptionsError(err) {
+				http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			utils.LogRequestErrorf(r, "Error getting sketches: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		// Always return a JSON array, even without sketches
+		if sketches == nil {
+			sketches = []model.SketchInfo{}
+		}
+
+		setNextPageLink(w, r, nextCursor)
+		if err := json.NewEncoder(w).Encode(sketches); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding JSON response for sketches: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Served %d sketches via API", len(sketches))
+	}
+}
+
+// GetMemberSketchesHandler handles GET requests to return a page of sketches for a specific member.
+// The next page (if any) is linked from the Link header.
 func GetMemberSketchesHandler(services *services.Services) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Set content type for JSON response
@@ -202,10 +315,21 @@ func GetMemberSketchesHandler(services *services.Services) http.HandlerFunc {
 			return
 		}
 
-		// Get all sketches for this member
-		sketches, err := services.Sketch.GetSketchesByMember(member.ID)
+		opts, err := parseListOptions(r.URL.Query())
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		// Get a page of sketches for this member (unlisted and private ones only for their owner)
+		includeHidden := viewerMemberID(r, services) == member.ID
+		sketches, nextCursor, err := services.Sketch.GetSketchesByMember(member.ID, includeHidden, opts)
 		if err != nil {
-			log.Printf("Error getting sketches for member %s: %v", memberName, err)
+			if isListOptionsError(err) {
+				http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			utils.LogRequestErrorf(r, "Error getting sketches for member %s: %v", memberName, err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
@@ -221,14 +345,17 @@ func GetMemberSketchesHandler(services *services.Services) http.HandlerFunc {
 				Keywords:     sketch.Keywords,
 				Tags:         sketch.Tags,
 				ExternalLibs: sketch.ExternalLibs,
+				Visibility:   sketch.Visibility,
+				Version:      sketch.Version,
 				CreatedAt:    sketch.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 				UpdatedAt:    sketch.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 			})
 		}
 
 		// Return JSON response
+		setNextPageLink(w, r, nextCursor)
 		if err := json.NewEncoder(w).Encode(sketchResponses); err != nil {
-			log.Printf("Error encoding JSON response for member sketches: %v", err)
+			utils.LogRequestErrorf(r, "Error encoding JSON response for member sketches: %v", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
@@ -237,6 +364,97 @@ func GetMemberSketchesHandler(services *services.Services) http.HandlerFunc {
 	}
 }
 
+// checkSourceCode parses submitted source code and returns its syntax errors and warnings.
+// In strict mode (?strict=1) code with syntax errors is not saved: it writes a 422 response
+// listing the errors and returns false.
+func checkSourceCode(w http.ResponseWriter, r *http.Request, sourceCode string) ([]jscode.Diagnostic, bool) {
+	diagnostics := jscode.Check(sourceCode)
+
+	strict, _ := strconv.ParseBool(r.URL.Query().Get("strict"))
+	if strict && jscode.HasErrors(diagnostics) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(SketchSyntaxErrorResponse{
+			Error:       "The source code has syntax errors",
+			Diagnostics: diagnostics,
+		})
+		return nil, false
+	}
+	return diagnostics, true
+}
+
+// sketchETag returns a strong ETag for a sketch: its version followed by a hash of its source
+// and external libraries, e.g. "3-5d41402abc4b2a76b9719d911017c592"
+func sketchETag(sketch *model.Sketch) string {
+	h := sha256.New()
+	io.WriteString(h, sketch.SourceCode)
+	for _, lib := range sketch.ExternalLibs {
+		h.Write([]byte{0})
+		io.WriteString(h, lib)
+	}
+	return fmt.Sprintf(`"%d-%s"`, sketch.Version, hex.EncodeToString(h.Sum(nil)[:16]))
+}
+
+// ifMatchesSketchVersion reports whether an If-Match header matches a sketch version. The header
+// holds the sketch's ETag, or just its version number in quotes ("3") for clients that only
+// have the version from a JSON response.
+func ifMatchesSketchVersion(ifMatch string, version int) bool {
+	if strings.TrimSpace(ifMatch) == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		// If-Match only uses strong comparison, so weak tags never match
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		tagVersion, _, _ := strings.Cut(strings.Trim(tag, `"`), "-")
+		if v, err := strconv.Atoi(tagVersion); err == nil && v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSketchIfMatch checks that a write to a sketch is based on its current version.
+// It writes a 428 response when the If-Match header is missing, or a 412 response with
+// the current sketch when it does not match, and then returns false.
+func checkSketchIfMatch(w http.ResponseWriter, r *http.Request, sketch *model.Sketch) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		http.Error(w, `{"error":"If-Match header with the sketch version is required"}`, http.StatusPreconditionRequired)
+		return false
+	}
+	if !ifMatchesSketchVersion(ifMatch, sketch.Version) {
+		writeSketchConflict(w, sketch)
+		return false
+	}
+	return true
+}
+
+// writeSketchConflict answers a write based on an old version of a sketch with 412 Precondition Failed
+// and the current sketch, so that the member's changes can be merged instead of lost
+func writeSketchConflict(w http.ResponseWriter, current *model.Sketch) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", sketchETag(current))
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(SketchConflictResponse{
+		Error:   fmt.Sprintf("The sketch was saved again since you opened it (it is now at version %d)", current.Version),
+		Current: current,
+	})
+}
+
+// writeLatestSketchConflict answers with a 412 response when a sketch was saved by another
+// request between checking If-Match and updating it
+func writeLatestSketchConflict(w http.ResponseWriter, services *services.Services, sketchID int) {
+	current, err := services.Sketch.GetSketchByID(sketchID)
+	if err != nil {
+		utils.LogErrorf("Error getting sketch %d after a version conflict: %v", sketchID, err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	writeSketchConflict(w, current)
+}
+
 // SketchCodeHandler handles requests to serve JavaScript files from the database.
 // This handler serves the JS source code stored in the database for a specific sketch.
 func SketchCodeHandler(services *services.Services) http.HandlerFunc {
@@ -272,21 +490,37 @@ func SketchCodeHandler(services *services.Services) http.HandlerFunc {
 			return
 		}
 
+		// Private and hidden sketches are only served to their owner
+		if !sketch.IsViewableBy(viewerMemberID(r, services)) {
+			log.Printf("Sketch %s by member %s is private or hidden", sketchSlug, memberName)
+			http.NotFound(w, r)
+			return
+		}
+
 		// Set appropriate content type for JavaScript
 		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
 
-		// Set cache headers to allow reasonable caching but still allow updates
-		w.Header().Set("Cache-Control", "public, max-age=300") // 5 minutes cache
+		// Caches must revalidate the code on every use, so that the editor and viewers never
+		// run an old version; unchanged code is answered with 304 Not Modified.
+		// Private and hidden sketches must not be stored by shared caches.
+		if sketch.Visibility == model.VisibilityPrivate || sketch.Hidden {
+			w.Header().Set("Cache-Control", "private, no-cache")
+		} else {
+			w.Header().Set("Cache-Control", "public, no-cache")
+		}
+		if utils.CheckNotModified(w, r, sketchETag(sketch), sketch.UpdatedAt) {
+			return
+		}
 
 		// Write the JavaScript source code
 		_, err = w.Write([]byte(sketch.SourceCode))
 		if err != nil {
-			log.Printf("Error writing JavaScript response for sketch %s/%s: %v", memberName, sketchSlug, err)
+			utils.LogRequestErrorf(r, "Error writing JavaScript response for sketch %s/%s: %v", memberName, sketchSlug, err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 
-		log.Printf("Served JavaScript for sketch: %s/%s (from database)", memberName, sketchSlug)
+		log.Printf("Served JavaScript for sketch: %s/%s", memberName, sketchSlug)
 	}
 }
 
@@ -324,7 +558,7 @@ func CreateSketchHandler(services *services.Services) http.HandlerFunc {
 		// Parse request body
 		var req SketchCreateRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Printf("Error decoding create sketch request: %v", err)
+			utils.LogRequestErrorf(r, "Error decoding create sketch request: %v", err)
 			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
 			return
 		}
@@ -335,10 +569,15 @@ func CreateSketchHandler(services *services.Services) http.HandlerFunc {
 			return
 		}
 
+		diagnostics, ok := checkSourceCode(w, r, req.SourceCode)
+		if !ok {
+			return
+		}
+
 		// Generate unique timestamp-based slug
 		sketchSlug, err := generateTimestampSlug(services, memberID)
 		if err != nil {
-			log.Printf("Error generating timestamp slug for member %d: %v", memberID, err)
+			utils.LogRequestErrorf(r, "Error generating timestamp slug for member %d: %v", memberID, err)
 			http.Error(w, `{"error":"Failed to generate unique sketch name"}`, http.StatusInternalServerError)
 			return
 		}
@@ -356,14 +595,16 @@ func CreateSketchHandler(services *services.Services) http.HandlerFunc {
 		// Create sketch with generated slug
 		sketch, err := services.Sketch.CreateSketchWithSlug(memberID, createReq, sketchSlug)
 		if err != nil {
-			log.Printf("Error creating sketch for member %d: %v", memberID, err)
+			utils.LogRequestErrorf(r, "Error creating sketch for member %d: %v", memberID, err)
 			http.Error(w, `{"error":"Failed to create sketch"}`, http.StatusInternalServerError)
 			return
 		}
+		metrics.IncSketchSaves("create")
 
-		// Return created sketch
-		if err := json.NewEncoder(w).Encode(sketch); err != nil {
-			log.Printf("Error encoding sketch response: %v", err)
+		// Return created sketch and the problems in its code, with its ETag for the next write
+		w.Header().Set("ETag", sketchETag(sketch))
+		if err := json.NewEncoder(w).Encode(SketchSaveResponse{Sketch: sketch, Diagnostics: diagnostics}); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding sketch response: %v", err)
 			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 			return
 		}
@@ -413,7 +654,7 @@ func UpdateSketchHandler(services *services.Services) http.HandlerFunc {
 		// Parse request body
 		var req SketchUpdateRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Printf("Error decoding update sketch request: %v", err)
+			utils.LogRequestErrorf(r, "Error decoding update sketch request: %v", err)
 			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
 			return
 		}
@@ -424,22 +665,43 @@ func UpdateSketchHandler(services *services.Services) http.HandlerFunc {
 			return
 		}
 
+		diagnostics, ok := checkSourceCode(w, r, req.SourceCode)
+		if !ok {
+			return
+		}
+
+		// Refuse saves based on an old version, so that changes made in another tab are not lost
+		if !checkSketchIfMatch(w, r, sketch) {
+			return
+		}
+
 		// Create update request (only source code)
 		updateReq := &model.UpdateSketchRequest{
-			SourceCode: &req.SourceCode,
+			SourceCode:      &req.SourceCode,
+			ExpectedVersion: &sketch.Version,
 		}
 
 		// Update sketch
 		updatedSketch, err := services.Sketch.UpdateSketch(sketch.ID, updateReq)
 		if err != nil {
-			log.Printf("Error updating sketch %s for member %s: %v", sketchSlug, memberName, err)
+			if err.Error() == "sketch version conflict" {
+				writeLatestSketchConflict(w, services, sketch.ID)
+				return
+			}
+			if err.Error() == "sketch not found" {
+				http.Error(w, `{"error":"Sketch not found"}`, http.StatusNotFound)
+				return
+			}
+			utils.LogRequestErrorf(r, "Error updating sketch %s for member %s: %v", sketchSlug, memberName, err)
 			http.Error(w, `{"error":"Failed to update sketch"}`, http.StatusInternalServerError)
 			return
 		}
+		metrics.IncSketchSaves("update")
 
-		// Return updated sketch
-		if err := json.NewEncoder(w).Encode(updatedSketch); err != nil {
-			log.Printf("Error encoding updated sketch response: %v", err)
+		// Return updated sketch and the problems in its code, with its new ETag for the next write
+		w.Header().Set("ETag", sketchETag(updatedSketch))
+		if err := json.NewEncoder(w).Encode(SketchSaveResponse{Sketch: updatedSketch, Diagnostics: diagnostics}); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding updated sketch response: %v", err)
 			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 			return
 		}
@@ -489,7 +751,7 @@ func UpdateSketchMetadataHandler(services *services.Services) http.HandlerFunc {
 		// Parse request body
 		var req SketchMetadataUpdateRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Printf("Error decoding update sketch metadata request: %v", err)
+			utils.LogRequestErrorf(r, "Error decoding update sketch metadata request: %v", err)
 			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
 			return
 		}
@@ -521,27 +783,50 @@ func UpdateSketchMetadataHandler(services *services.Services) http.HandlerFunc {
 			http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusBadRequest)
 			return
 		}
+		if req.Visibility != "" && !model.IsValidVisibility(req.Visibility) {
+			http.Error(w, `{"error":"visibility must be one of public, unlisted or private"}`, http.StatusBadRequest)
+			return
+		}
+
+		// Refuse changes based on an old version, as for source code saves
+		if !checkSketchIfMatch(w, r, sketch) {
+			return
+		}
 
 		// Create update request (only metadata fields)
 		updateReq := &model.UpdateSketchRequest{
-			Title:        &req.Title,
-			Description:  &req.Description,
-			Keywords:     &req.Keywords,
-			Tags:         req.Tags,
-			ExternalLibs: req.ExternalLibs,
+			Title:           &req.Title,
+			Description:     &req.Description,
+			Keywords:        &req.Keywords,
+			Tags:            req.Tags,
+			ExternalLibs:    req.ExternalLibs,
+			ExpectedVersion: &sketch.Version,
+		}
+		if req.Visibility != "" {
+			updateReq.Visibility = &req.Visibility
 		}
 
 		// Update sketch metadata (this will also update the slug and updated_at automatically)
 		updatedSketch, err := services.Sketch.UpdateSketch(sketch.ID, updateReq)
 		if err != nil {
-			log.Printf("Error updating sketch metadata %s for member %s: %v", sketchSlug, memberName, err)
+			if err.Error() == "sketch version conflict" {
+				writeLatestSketchConflict(w, services, sketch.ID)
+				return
+			}
+			if err.Error() == "sketch not found" {
+				http.Error(w, `{"error":"Sketch not found"}`, http.StatusNotFound)
+				return
+			}
+			utils.LogRequestErrorf(r, "Error updating sketch metadata %s for member %s: %v", sketchSlug, memberName, err)
 			http.Error(w, `{"error":"Failed to update sketch metadata"}`, http.StatusInternalServerError)
 			return
 		}
+		metrics.IncSketchSaves("update_metadata")
 
-		// Return updated sketch
+		// Return updated sketch, with its new ETag for the next write
+		w.Header().Set("ETag", sketchETag(updatedSketch))
 		if err := json.NewEncoder(w).Encode(updatedSketch); err != nil {
-			log.Printf("Error encoding updated sketch response: %v", err)
+			utils.LogRequestErrorf(r, "Error encoding updated sketch response: %v", err)
 			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 			return
 		}
@@ -591,7 +876,7 @@ func DeleteSketchHandler(services *services.Services) http.HandlerFunc {
 		// Delete sketch
 		err = services.Sketch.DeleteSketch(sketch.ID)
 		if err != nil {
-			log.Printf("Error deleting sketch %s for member %s: %v", sketchSlug, memberName, err)
+			utils.LogRequestErrorf(r, "Error deleting sketch %s for member %s: %v", sketchSlug, memberName, err)
 			http.Error(w, `{"error":"Failed to delete sketch"}`, http.StatusInternalServerError)
 			return
 		}
@@ -603,3 +888,55 @@ func DeleteSketchHandler(services *services.Services) http.HandlerFunc {
 		log.Printf("Deleted sketch %s for member %s", sketchSlug, memberName)
 	}
 }
+
+// ForkSketchHandler handles POST requests to fork (remix) a sketch into the authenticated member's account
+func ForkSketchHandler(services *services.Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Set content type for JSON response
+		w.Header().Set("Content-Type", "application/json")
+
+		// Get authenticated member ID from context (set by authMiddleware)
+		memberID, ok := r.Context().Value("authenticated_member_id").(int)
+		if !ok {
+			http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+			return
+		}
+
+		// Get the sketch being forked
+		original, ok := getSketchFromPath(w, r, services)
+		if !ok {
+			return
+		}
+		// Owners can still see sketches a moderator hid, but not copy them into a visible sketch
+		if original.Hidden {
+			log.Printf("Refusing to fork hidden sketch %d for member %d", original.ID, memberID)
+			http.Error(w, `{"error":"Sketch not found"}`, http.StatusNotFound)
+			return
+		}
+
+		// Generate unique timestamp-based slug in the forking member's account
+		forkSlug, err := generateTimestampSlug(services, memberID)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error generating timestamp slug for member %d: %v", memberID, err)
+			http.Error(w, `{"error":"Failed to generate unique sketch name"}`, http.StatusInternalServerError)
+			return
+		}
+
+		fork, err := services.Sketch.ForkSketch(original, memberID, forkSlug)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error forking sketch %d for member %d: %v", original.ID, memberID, err)
+			http.Error(w, `{"error":"Failed to fork sketch"}`, http.StatusInternalServerError)
+			return
+		}
+		metrics.IncSketchSaves("fork")
+
+		// Return the new sketch
+		if err := json.NewEncoder(w).Encode(fork); err != nil {
+			utils.LogRequestErrorf(r, "Error encoding forked sketch response: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Forked sketch %d into %s for member %d", original.ID, forkSlug, memberID)
+	}
+}
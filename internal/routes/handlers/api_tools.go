@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/jscode"
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// maxFormatRequestSize limits the body of format requests to what the longest sketch can take
+// once encoded in JSON, where a character takes at most 6 bytes (a control character is escaped
+// as \u001f), plus room for the rest of the request
+const maxFormatRequestSize = 6*model.MaxSourceCodeLength + 1024
+
+// FormatCodeRequest represents the request body for formatting source code
+type FormatCodeRequest struct {
+	SourceCode string `json:"source_code"`
+}
+
+// FormatCodeResponse holds the formatted source code, or the original one when it has syntax errors
+type FormatCodeResponse struct {
+	SourceCode  string              `json:"source_code"`
+	Diagnostics []jscode.Diagnostic `json:"diagnostics"`
+}
+
+// FormatCodeHandler handles POST requests to format sketch source code the same way for everyone.
+// Code with syntax errors cannot be formatted, and is answered with 422 and the errors.
+// It is for members only, as parsing and formatting large sketches costs CPU.
+func FormatCodeHandler(w http.ResponseWriter, r *http.Request) {
+	// Set content type for JSON response
+	w.Header().Set("Content-Type", "application/json")
+
+	var req FormatCodeRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormatRequestSize)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, `{"error":"Source code is too large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		utils.LogRequestErrorf(r, "Error decoding format code request: %v", err)
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if utf8.RuneCountInString(req.SourceCode) > model.MaxSourceCodeLength {
+		http.Error(w, `{"error":"Source code is too large"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	formatted, diagnostics := jscode.Format(req.SourceCode)
+	if jscode.HasErrors(diagnostics) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	if err := json.NewEncoder(w).Encode(FormatCodeResponse{SourceCode: formatted, Diagnostics: diagnostics}); err != nil {
+		utils.LogRequestErrorf(r, "Error encoding format code response: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/services"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// Admin page handlers
+// These are only routed through requireRole, so the authenticated member is at least a moderator
+
+// AdminDashboardPageData holds data for the admin dashboard
+type AdminDashboardPageData struct {
+	utils.PageData
+	Stats          *model.AdminStats
+	PendingMembers []model.Member
+}
+
+// AdminMembersPageData holds data for the admin member list
+type AdminMembersPageData struct {
+	utils.PageData
+	Members []model.Member
+	IsAdmin bool // Admins can also change roles and reset passwords
+	Roles   []string
+}
+
+// AdminSketchesPageData holds data for the admin sketch list
+type AdminSketchesPageData struct {
+	utils.PageData
+	Sketches    []model.ModerationSketch
+	NextPageURL string
+}
+
+// renderAdminPage executes an admin page template
+func renderAdminPage(w http.ResponseWriter, tmpl *template.Template, name string, data interface{}) {
+	tmplClone, err := tmpl.Clone()
+	if err != nil {
+		http.Error(w, "Error cloning template", http.StatusInternalServerError)
+		utils.LogErrorf("Error cloning template for %s: %v", name, err)
+		return
+	}
+
+	if err := tmplClone.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, "Error rendering "+name+" template", http.StatusInternalServerError)
+		utils.LogErrorf("Error rendering %s template: %v", name, err)
+	}
+}
+
+// AdminDashboardPageHandler shows the club counts and the members waiting for approval
+func AdminDashboardPageHandler(services *services.Services) func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
+	return func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
+		stats, err := services.Admin.GetStats()
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error getting admin stats: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		pendingMembers, err := services.Member.GetUnverifiedMembers()
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error getting members awaiting approval: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		pageData.Title = utils.Translate(pageData.Lang, "pages.admin.meta.title")
+		pageData.Description = utils.Translate(pageData.Lang, "pages.admin.meta.description")
+
+		renderAdminPage(w, tmpl, "page-admin", AdminDashboardPageData{
+			PageData:       *pageData,
+			Stats:          stats,
+			PendingMembers: pendingMembers,
+		})
+	}
+}
+
+// AdminMembersPageHandler lists all members with their role and status
+func AdminMembersPageHandler(services *services.Services) func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
+	return func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
+		memberID, _ := r.Context().Value("authenticated_member_id").(int)
+		currentMember, err := services.Member.GetMemberByID(memberID)
+		if err != nil {
+			http.Redirect(w, r, "/sign-in", http.StatusSeeOther)
+			return
+		}
+
+		members, err := services.Member.GetAllMembers()
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error getting all members: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		pageData.Title = utils.Translate(pageData.Lang, "pages.admin.members.meta.title")
+		pageData.Description = utils.Translate(pageData.Lang, "pages.admin.meta.description")
+
+		renderAdminPage(w, tmpl, "page-admin-members", AdminMembersPageData{
+			PageData: *pageData,
+			Members:  members,
+			IsAdmin:  currentMember.HasRole(model.RoleAdmin),
+			Roles:    []string{model.RoleMember, model.RoleModerator, model.RoleAdmin},
+		})
+	}
+}
+
+// AdminSketchesPageHandler lists sketches from all members, whatever their visibility
+func AdminSketchesPageHandler(services *services.Services) func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
+	return func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
+		opts, err := parseListOptions(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sketches, nextCursor, err := services.Sketch.GetSketchesForModeration(opts)
+		if err != nil {
+			if isListOptionsError(err) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			utils.LogRequestErrorf(r, "Error getting sketches for moderation: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		pageData.Title = utils.Translate(pageData.Lang, "pages.admin.sketches.meta.title")
+		pageData.Description = utils.Translate(pageData.Lang, "pages.admin.meta.description")
+
+		renderAdminPage(w, tmpl, "page-admin-sketches", AdminSketchesPageData{
+			PageData:    *pageData,
+			Sketches:    sketches,
+			NextPageURL: nextPageURL(r, nextCursor),
+		})
+	}
+}
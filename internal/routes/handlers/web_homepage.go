@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"html/template"
-	"log"
 	"net/http"
 
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
@@ -12,12 +11,12 @@ func HomePageGetHandler(w http.ResponseWriter, r *http.Request, tmpl *template.T
 	tmplClone, err := tmpl.Clone()
 	if err != nil {
 		http.Error(w, "Error cloning template for homepage", http.StatusInternalServerError)
-		log.Printf("Error cloning template for homepage: %v", err)
+		utils.LogRequestErrorf(r, "Error cloning template for homepage: %v", err)
 		return
 	}
 
 	if err := tmplClone.ExecuteTemplate(w, "page-homepage", pageData); err != nil {
 		http.Error(w, "Error rendering page-homepage template", http.StatusInternalServerError)
-		log.Printf("Error rendering page-homepage template: %v", err)
+		utils.LogRequestErrorf(r, "Error rendering page-homepage template: %v", err)
 	}
 }
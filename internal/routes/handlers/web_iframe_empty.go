@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"html/template"
-	"log"
 	"net/http"
 
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
@@ -16,12 +15,12 @@ func EmptyIframeHandler(w http.ResponseWriter, r *http.Request, tmpl *template.T
 	tmplClone, err := tmpl.Clone()
 	if err != nil {
 		http.Error(w, "Error cloning template for empty iframe", http.StatusInternalServerError)
-		log.Printf("Error cloning template for empty iframe: %v", err)
+		utils.LogRequestErrorf(r, "Error cloning template for empty iframe: %v", err)
 		return
 	}
 
 	if err := tmplClone.ExecuteTemplate(w, "page-iframe-empty", pageData); err != nil {
 		http.Error(w, "Error rendering page-iframe-empty template", http.StatusInternalServerError)
-		log.Printf("Error rendering page-iframe-empty template: %v", err)
+		utils.LogRequestErrorf(r, "Error rendering page-iframe-empty template: %v", err)
 	}
 }
@@ -47,6 +47,13 @@ func SketchIframeContentHandler(services *services.Services) func(w http.Respons
 			return
 		}
 
+		// Private and hidden sketches are reported as missing to everyone but their owner
+		if !sketch.IsViewableBy(viewerMemberID(r, services)) {
+			log.Printf("Sketch %s/%s is private or hidden", memberName, sketchSlug)
+			http.Error(w, "Sketch not found", http.StatusNotFound)
+			return
+		}
+
 		// Point to the database-served JavaScript endpoint
 		sketchJsPath := "/api/sketches/" + memberName + "/" + sketchSlug
 
@@ -65,9 +72,9 @@ func SketchIframeContentHandler(services *services.Services) func(w http.Respons
 		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
 		w.Header().Set("Content-Security-Policy", "default-src 'self' https:; script-src 'self' 'unsafe-eval' 'unsafe-inline' https:; style-src 'self' 'unsafe-inline';")
 
-		err = tmpl.ExecuteTemplate(w, "page-iframe-sketch", templateData)
+		err = renderSketchPage(w, r, tmpl, "page-iframe-sketch", templateData, sketch)
 		if err != nil {
-			log.Printf("Error executing page-iframe-sketch template: %v", err)
+			utils.LogRequestErrorf(r, "Error executing page-iframe-sketch template: %v", err)
 			http.Error(w, "Internal Server Error executing template", http.StatusInternalServerError)
 		}
 	}
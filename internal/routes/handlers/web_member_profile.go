@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/services"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// MemberProfilePageData holds data for a member's public profile page
+type MemberProfilePageData struct {
+	utils.PageData
+	Name         string
+	Bio          string
+	AvatarURL    string
+	Links        []string
+	JoinedAt     time.Time
+	IsOwnProfile bool            // Whether the viewer is the member (to link to the edit form)
+	Sketches     []*model.Sketch // Current page of the member's public sketches
+	NextPageURL  string          // Link to the next page of sketches (empty on the last page)
+}
+
+// MemberProfilePageHandler shows a member's public profile and their public sketches.
+// Sketches are paged like /api/sketches/{memberName} (?cursor=&sort=&tag=&lib=).
+func MemberProfilePageHandler(services *services.Services) func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
+	return func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
+		memberName := utils.PathVariable(r, "memberName")
+
+		if services == nil {
+			log.Printf("Services not initialized")
+			NotFoundHandler(w, r, tmpl, pageData)
+			return
+		}
+
+		member, err := services.Member.GetMemberByName(memberName)
+		if err != nil {
+			log.Printf("Member not found: %s", memberName)
+			NotFoundHandler(w, r, tmpl, pageData)
+			return
+		}
+
+		templateData := MemberProfilePageData{
+			Name:         member.Name,
+			Bio:          member.Bio,
+			AvatarURL:    member.AvatarURL,
+			Links:        member.Links,
+			JoinedAt:     member.CreatedAt,
+			IsOwnProfile: pageData.IsAuthenticated && pageData.MemberName == member.Name,
+		}
+
+		// Invalid paging parameters fall back to the first page
+		opts, err := parseListOptions(r.URL.Query())
+		if err != nil {
+			opts = &model.ListSketchesOptions{}
+		}
+		sketches, nextCursor, err := services.Sketch.GetSketchesByMember(member.ID, false, opts)
+		if err != nil && isListOptionsError(err) {
+			sketches, nextCursor, err = services.Sketch.GetSketchesByMember(member.ID, false, nil)
+		}
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error getting sketches for member %s: %v", memberName, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		templateData.Sketches = sketches
+		templateData.NextPageURL = nextPageURL(r, nextCursor)
+
+		pageData.Title = utils.Translate(pageData.Lang, "pages.memberProfile.meta.title", member.Name)
+		pageData.Description = utils.Translate(pageData.Lang, "pages.memberProfile.meta.description", member.Name)
+		if member.Bio != "" {
+			pageData.Description = member.Bio
+		}
+		pageData.OgType = "profile"
+		templateData.PageData = *pageData
+
+		if err := tmpl.ExecuteTemplate(w, "page-member-profile", templateData); err != nil {
+			utils.LogRequestErrorf(r, "Error executing page-member-profile template: %v", err)
+			http.Error(w, "Internal Server Error executing template", http.StatusInternalServerError)
+		}
+	}
+}
+
+// MemberSketchRedirectHandler permanently redirects old /members/{memberName}/{sketchSlug}
+// sketch URLs to the canonical /sketches/{memberName}/{sketchSlug} URLs
+func MemberSketchRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	memberName := utils.PathVariable(r, "memberName")
+	sketchSlug := utils.PathVariable(r, "sketchSlug")
+
+	target := "/sketches/" + url.PathEscape(memberName) + "/" + url.PathEscape(sketchSlug)
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"html/template"
-	"log"
 	"net/http"
 
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
@@ -19,7 +18,7 @@ func NotFoundHandler(w http.ResponseWriter, r *http.Request, tmpl *template.Temp
 	// Clone the master template for this request.
 	clonedMasterTmpl, err := tmpl.Clone()
 	if err != nil {
-		log.Printf("Error cloning template in NotFoundHandler: %v", err)
+		utils.LogRequestErrorf(r, "Error cloning template in NotFoundHandler: %v", err)
 		http.Error(w, utils.Translate(pageData.Lang, "pages.notFound.title")+" - Error preparing page", http.StatusInternalServerError)
 		return
 	}
@@ -27,7 +26,7 @@ func NotFoundHandler(w http.ResponseWriter, r *http.Request, tmpl *template.Temp
 	// Execute the specific page template, passing pageData directly.
 	err = clonedMasterTmpl.ExecuteTemplate(w, "page-not-found", pageData)
 	if err != nil {
-		log.Printf("Error executing page-not-found template: %v", err)
+		utils.LogRequestErrorf(r, "Error executing page-not-found template: %v", err)
 		// Fallback to a simpler error if the 404 template itself fails
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"html/template"
-	"log"
 	"net/http"
 
 	"github.com/sb-luis/creative-coding-bookclub/internal/services"
@@ -12,8 +11,13 @@ import (
 // ProfilePageData holds data for the profile page
 type ProfilePageData struct {
 	utils.PageData
-	Name     string
-	MemberID int
+	Name      string
+	MemberID  int
+	Verified  bool // Unverified members are waiting in the approval queue
+	Bio       string
+	AvatarURL string
+	Links     []string
+	Sessions  []SessionResponse // Active sessions, most recently used first
 }
 
 // ProfileHandler shows the authenticated member's profile
@@ -38,25 +42,35 @@ func ProfileHandler(services *services.Services) func(w http.ResponseWriter, r *
 			return
 		}
 
+		sessions, err := services.Session.GetMemberSessions(memberID)
+		if err != nil {
+			utils.LogRequestErrorf(r, "Error getting sessions for member %d: %v", memberID, err)
+		}
+
 		pageData.Title = utils.Translate(pageData.Lang, "pages.profile.meta.title")
 		pageData.Description = utils.Translate(pageData.Lang, "pages.profile.meta.description")
 
 		templateData := ProfilePageData{
-			PageData: *pageData,
-			Name:     member.Name,
-			MemberID: member.ID,
+			PageData:  *pageData,
+			Name:      member.Name,
+			MemberID:  member.ID,
+			Verified:  member.Verified,
+			Bio:       member.Bio,
+			AvatarURL: member.AvatarURL,
+			Links:     member.Links,
+			Sessions:  newSessionResponses(sessions, sessionID),
 		}
 
 		tmplClone, err := tmpl.Clone()
 		if err != nil {
 			http.Error(w, "Error cloning template", http.StatusInternalServerError)
-			log.Printf("Error cloning template for profile: %v", err)
+			utils.LogRequestErrorf(r, "Error cloning template for profile: %v", err)
 			return
 		}
 
 		if err := tmplClone.ExecuteTemplate(w, "page-profile", templateData); err != nil {
 			http.Error(w, "Error rendering page-profile template", http.StatusInternalServerError)
-			log.Printf("Error rendering page-profile template: %v", err)
+			utils.LogRequestErrorf(r, "Error rendering page-profile template: %v", err)
 		}
 	}
 }
@@ -4,7 +4,9 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"strings"
 
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services"
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
 )
@@ -12,8 +14,17 @@ import (
 // RegisterPageData holds data for the register page
 type RegisterPageData struct {
 	utils.PageData
-	Error   string
-	Success string
+	Error      string
+	Success    string
+	InviteCode string // Prefilled from the ?code= query parameter of invite links
+}
+
+// inviteCodeErrors maps invite redemption errors from the member service to messages for the form
+var inviteCodeErrors = map[string]string{
+	"invalid invite code":                   "This invite code is not valid",
+	"invite code expired":                   "This invite code has expired",
+	"invite code used up":                   "This invite code has already been used",
+	"invite code reserved for another name": "This invite code is reserved for a different name",
 }
 
 // RegisterGetHandler shows the registration form
@@ -22,19 +33,20 @@ func RegisterGetHandler(w http.ResponseWriter, r *http.Request, tmpl *template.T
 	pageData.Description = utils.Translate(pageData.Lang, "pages.register.meta.description")
 
 	templateData := RegisterPageData{
-		PageData: *pageData,
+		PageData:   *pageData,
+		InviteCode: r.URL.Query().Get("code"),
 	}
 
 	tmplClone, err := tmpl.Clone()
 	if err != nil {
 		http.Error(w, "Error cloning template", http.StatusInternalServerError)
-		log.Printf("Error cloning template for register: %v", err)
+		utils.LogRequestErrorf(r, "Error cloning template for register: %v", err)
 		return
 	}
 
 	if err := tmplClone.ExecuteTemplate(w, "page-register", templateData); err != nil {
 		http.Error(w, "Error rendering page-register template", http.StatusInternalServerError)
-		log.Printf("Error rendering page-register template: %v", err)
+		utils.LogRequestErrorf(r, "Error rendering page-register template: %v", err)
 	}
 }
 
@@ -52,9 +64,11 @@ func RegisterPostHandler(services *services.Services) func(w http.ResponseWriter
 		name := r.FormValue("name")
 		password := r.FormValue("password")
 		confirmPassword := r.FormValue("confirm_password")
+		inviteCode := strings.TrimSpace(r.FormValue("invite_code"))
 
 		templateData := RegisterPageData{
-			PageData: *pageData,
+			PageData:   *pageData,
+			InviteCode: inviteCode,
 		}
 
 		// Validation
@@ -69,28 +83,52 @@ func RegisterPostHandler(services *services.Services) func(w http.ResponseWriter
 				templateData.Error = "Unable to create account. Please try again."
 			} else {
 				// Hash the password
-				passwordHash := utils.HashPassword(password)
-
-				// Create the member
-				member, err := services.Member.CreateMember(name, passwordHash)
+				passwordHash, err := utils.HashPassword(password)
 				if err != nil {
-					// Log the actual error for debugging
-					log.Printf("Failed to create member account for name '%s': %v", name, err)
-
-					// Show generic error message to user
+					utils.LogRequestErrorf(r, "Failed to hash password for new member '%s': %v", name, err)
 					templateData.Error = "Unable to create account. Please try again."
 				} else {
-					// Account created successfully, sign in the user
-					session, err := services.Session.CreateSession(member.ID)
+					// With a valid invite code the member is verified straight away,
+					// otherwise they wait in the approval queue
+					var member *model.Member
+					if inviteCode != "" {
+						member, err = services.Member.CreateMemberWithInviteCode(name, passwordHash, inviteCode)
+					} else {
+						member, err = services.Member.CreateMember(name, passwordHash)
+					}
+
 					if err != nil {
-						log.Printf("Failed to create session for new member %d: %v", member.ID, err)
-						templateData.Error = "Account created but unable to sign in. Please try signing in manually."
+						// Log the actual error for debugging
+						utils.LogRequestErrorf(r, "Failed to create member account for name '%s': %v", name, err)
+
+						if message, ok := inviteCodeErrors[err.Error()]; ok {
+							templateData.Error = message
+						} else {
+							// Show generic error message to user
+							templateData.Error = "Unable to create account. Please try again."
+						}
 					} else {
-						// Set session cookie and redirect to homepage
-						utils.SetSessionCookie(w, session.ID)
-						log.Printf("Member %s registered and automatically signed in", member.Name)
-						http.Redirect(w, r, "/", http.StatusSeeOther)
-						return
+						// Account created successfully, sign in the user
+						session, err := services.Session.CreateSession(member.ID, &model.CreateSessionRequest{
+							UserAgent: r.UserAgent(),
+							IP:        utils.GetClientIP(r),
+						})
+						if err != nil {
+							utils.LogRequestErrorf(r, "Failed to create session for new member %d: %v", member.ID, err)
+							templateData.Error = "Account created but unable to sign in. Please try signing in manually."
+						} else {
+							// Set session cookie and redirect to homepage (or to the profile
+							// page, which explains the approval queue, for unverified members)
+							utils.SetSessionCookie(w, session.ID, false)
+							if member.Verified {
+								log.Printf("Member %s registered with an invite code and automatically signed in", member.Name)
+								http.Redirect(w, r, "/", http.StatusSeeOther)
+							} else {
+								log.Printf("Member %s registered, awaiting approval, and automatically signed in", member.Name)
+								http.Redirect(w, r, "/me", http.StatusSeeOther)
+							}
+							return
+						}
 					}
 				}
 			}
@@ -99,13 +137,13 @@ func RegisterPostHandler(services *services.Services) func(w http.ResponseWriter
 		tmplClone, err := tmpl.Clone()
 		if err != nil {
 			http.Error(w, "Error cloning template", http.StatusInternalServerError)
-			log.Printf("Error cloning template for register: %v", err)
+			utils.LogRequestErrorf(r, "Error cloning template for register: %v", err)
 			return
 		}
 
 		if err := tmplClone.ExecuteTemplate(w, "page-register", templateData); err != nil {
 			http.Error(w, "Error rendering page-register template", http.StatusInternalServerError)
-			log.Printf("Error rendering page-register template: %v", err)
+			utils.LogRequestErrorf(r, "Error rendering page-register template: %v", err)
 		}
 	}
 }
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// ServerErrorPageData holds data for the internal server error page
+type ServerErrorPageData struct {
+	utils.PageData
+	RequestID string // Lets members quote the failed request when reporting it
+}
+
+// ServerErrorHandler renders the 500 page, e.g. after a handler panicked
+func ServerErrorHandler(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+
+	pageData.Title = utils.Translate(pageData.Lang, "pages.serverError.meta.title")
+	pageData.Description = utils.Translate(pageData.Lang, "pages.serverError.meta.description")
+	pageData.Icon = "💥"
+
+	data := ServerErrorPageData{PageData: *pageData}
+	if info := utils.GetRequestInfo(r); info != nil {
+		data.RequestID = info.ID
+	}
+
+	// Clone the master template for this request.
+	clonedMasterTmpl, err := tmpl.Clone()
+	if err != nil {
+		utils.LogRequestErrorf(r, "Error cloning template in ServerErrorHandler: %v", err)
+		w.Write([]byte("Internal Server Error"))
+		return
+	}
+
+	if err := clonedMasterTmpl.ExecuteTemplate(w, "page-server-error", data); err != nil {
+		utils.LogRequestErrorf(r, "Error executing page-server-error template: %v", err)
+	}
+}
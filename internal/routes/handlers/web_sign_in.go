@@ -3,8 +3,12 @@ package handlers
 import (
 	"html/template"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services"
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
 )
@@ -27,13 +31,13 @@ func SignInGetHandler(w http.ResponseWriter, r *http.Request, tmpl *template.Tem
 	tmplClone, err := tmpl.Clone()
 	if err != nil {
 		http.Error(w, "Error cloning template", http.StatusInternalServerError)
-		log.Printf("Error cloning template for sign-in: %v", err)
+		utils.LogRequestErrorf(r, "Error cloning template for sign-in: %v", err)
 		return
 	}
 
 	if err := tmplClone.ExecuteTemplate(w, "page-sign-in", templateData); err != nil {
 		http.Error(w, "Error rendering page-sign-in template", http.StatusInternalServerError)
-		log.Printf("Error rendering page-sign-in template: %v", err)
+		utils.LogRequestErrorf(r, "Error rendering page-sign-in template: %v", err)
 	}
 }
 
@@ -50,6 +54,8 @@ func SignInPostHandler(services *services.Services) func(w http.ResponseWriter,
 
 		name := r.FormValue("name")
 		password := r.FormValue("password")
+		ip := utils.GetClientIP(r)
+		status := http.StatusOK
 
 		templateData := SignInPageData{
 			PageData: *pageData,
@@ -61,22 +67,52 @@ func SignInPostHandler(services *services.Services) func(w http.ResponseWriter,
 			// Try to authenticate
 			if services == nil {
 				templateData.Error = "Service unavailable"
+			} else if wait := beginSignInAttempt(services, name, ip); wait > 0 {
+				// Too many failed attempts: refuse without checking the password
+				templateData.Error = signInLockoutMessage(pageData.Lang, wait)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				status = http.StatusTooManyRequests
 			} else {
 				// Get member by name
 				member, err := services.Member.GetMemberByName(name)
-				if err != nil {
-					templateData.Error = "Invalid name or password"
-				} else if !utils.VerifyPassword(password, member.PasswordHash) {
+				passwordOK := err == nil && utils.VerifyPassword(password, member.PasswordHash)
+
+				// The attempt was recorded as a failure before checking the password
+				if passwordOK {
+					if err := services.SignIn.ClearFailures(name); err != nil {
+						utils.LogRequestErrorf(r, "Error clearing failed sign-ins for '%s': %v", name, err)
+					}
+				}
+
+				if !passwordOK {
 					templateData.Error = "Invalid name or password"
+				} else if member.Suspended {
+					templateData.Error = "This account has been suspended"
 				} else {
+					// Transparently upgrade legacy or outdated password hashes
+					if utils.PasswordNeedsRehash(member.PasswordHash) {
+						if newHash, err := utils.HashPassword(password); err != nil {
+							utils.LogRequestErrorf(r, "Error rehashing password for member %d: %v", member.ID, err)
+						} else if err := services.Member.UpdatePasswordHash(member.ID, newHash); err != nil {
+							utils.LogRequestErrorf(r, "Error upgrading password hash for member %d: %v", member.ID, err)
+						} else {
+							log.Printf("Upgraded password hash for member %d", member.ID)
+						}
+					}
+
 					// Create session
-					session, err := services.Session.CreateSession(member.ID)
+					remember := r.FormValue("remember") != ""
+					session, err := services.Session.CreateSession(member.ID, &model.CreateSessionRequest{
+						UserAgent: r.UserAgent(),
+						IP:        ip,
+						Remember:  remember,
+					})
 					if err != nil {
 						templateData.Error = "Error creating session"
-						log.Printf("Error creating session for member %d: %v", member.ID, err)
+						utils.LogRequestErrorf(r, "Error creating session for member %d: %v", member.ID, err)
 					} else {
 						// Set session cookie and redirect
-						utils.SetSessionCookie(w, session.ID)
+						utils.SetSessionCookie(w, session.ID, remember)
 						http.Redirect(w, r, "/", http.StatusSeeOther)
 						return
 					}
@@ -87,13 +123,36 @@ func SignInPostHandler(services *services.Services) func(w http.ResponseWriter,
 		tmplClone, err := tmpl.Clone()
 		if err != nil {
 			http.Error(w, "Error cloning template", http.StatusInternalServerError)
-			log.Printf("Error cloning template for page-sign-in: %v", err)
+			utils.LogRequestErrorf(r, "Error cloning template for page-sign-in: %v", err)
 			return
 		}
 
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+		}
 		if err := tmplClone.ExecuteTemplate(w, "page-sign-in", templateData); err != nil {
 			http.Error(w, "Error rendering page-sign-in template", http.StatusInternalServerError)
-			log.Printf("Error rendering page-sign-in template: %v", err)
+			utils.LogRequestErrorf(r, "Error rendering page-sign-in template: %v", err)
 		}
 	}
 }
+
+// beginSignInAttempt returns how long the client must wait before trying to sign in as name again,
+// or records the attempt and returns 0. Sign-in is allowed if the attempts cannot be counted.
+func beginSignInAttempt(services *services.Services, name, ip string) time.Duration {
+	wait, err := services.SignIn.BeginAttempt(name, ip)
+	if err != nil {
+		utils.LogErrorf("Error checking sign-in rate limit for '%s' from %s: %v", name, ip, err)
+		return 0
+	}
+	return wait
+}
+
+// signInLockoutMessage tells the member how many minutes to wait before trying again
+func signInLockoutMessage(lang string, wait time.Duration) string {
+	minutes := int(math.Ceil(wait.Minutes()))
+	if minutes <= 1 {
+		return utils.Translate(lang, "pages.signIn.tooManyAttemptsOneMinute")
+	}
+	return utils.Translate(lang, "pages.signIn.tooManyAttempts", minutes)
+}
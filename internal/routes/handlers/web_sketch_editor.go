@@ -13,6 +13,7 @@ import (
 // SketchPageData holds all data for the sketch page template.
 type SketchPageData struct {
 	utils.PageData
+	SketchLineage
 	MemberName      string
 	SketchSlug      string
 	SketchJsPath    string
@@ -48,6 +49,13 @@ func SketchEditorPageHandler(services *services.Services) func(w http.ResponseWr
 			return
 		}
 
+		// Private and hidden sketches are reported as missing to everyone but their owner
+		if !sketch.IsViewableBy(viewerMemberID(r, services)) {
+			log.Printf("Sketch %s by member %s is private or hidden", sketchSlug, memberName)
+			NotFoundHandler(w, r, tmpl, pageData)
+			return
+		}
+
 		// Use metadata from database
 		if sketch.Title != "" {
 			pageData.Title = sketch.Title
@@ -64,11 +72,9 @@ func SketchEditorPageHandler(services *services.Services) func(w http.ResponseWr
 
 		// Get initial view mode from query parameter, default to 'overlay'
 		initialViewMode := r.URL.Query().Get("viewMode")
-		log.Printf("Raw viewMode query parameter: %q", initialViewMode)
 
 		// Clean up the view mode value (remove quotes if present)
 		initialViewMode = strings.Trim(initialViewMode, "\"")
-		log.Printf("Cleaned viewMode: %q", initialViewMode)
 
 		if initialViewMode == "" {
 			initialViewMode = "overlay"
@@ -85,10 +91,9 @@ func SketchEditorPageHandler(services *services.Services) func(w http.ResponseWr
 			initialViewMode = "overlay"
 		}
 
-		log.Printf("Final view mode: %q", initialViewMode)
-
 		templateData := SketchPageData{
 			PageData:        *pageData,
+			SketchLineage:   getSketchLineage(services, sketch),
 			MemberName:      memberName,
 			SketchSlug:      sketchSlug,
 			SketchJsPath:    sketchJsPath,
@@ -102,7 +107,7 @@ func SketchEditorPageHandler(services *services.Services) func(w http.ResponseWr
 
 		err = tmpl.ExecuteTemplate(w, "page-sketch-editor", templateData)
 		if err != nil {
-			log.Printf("Error executing page-sketch-editor template: %v", err)
+			utils.LogRequestErrorf(r, "Error executing page-sketch-editor template: %v", err)
 			http.Error(w, "Internal Server Error executing template", http.StatusInternalServerError)
 		}
 	}
@@ -4,19 +4,32 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 
 	"github.com/sb-luis/creative-coding-bookclub/internal/model"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services"
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
 )
 
+// SketchListerItem is a sketch shown in the lister, with its search snippet (if any) ready for rendering.
+type SketchListerItem struct {
+	model.SketchInfo
+	SnippetHTML template.HTML
+}
+
 // SketchListerPageData holds all data for the sketch lister page template.
 type SketchListerPageData struct {
 	utils.PageData
-	Sketches []model.SketchInfo
+	Sketches     []SketchListerItem
+	SearchQuery  string // Current search query (empty when listing all sketches)
+	ErrorMessage string // Why the search or listing parameters were rejected (if they were)
+	NextPageURL  string // Link to the next page of sketches (empty on the last page)
+	FirstPageURL string // Link to the first page of sketches (empty on the first page)
 }
 
 // SketchListerPageHandler handles requests to display the list of all sketches.
+// Sketches are paged like /api/sketches (?cursor=&sort=&tag=&lib=). When a search query is given
+// (?q=, plus the same filters as /api/search) only matching sketches are listed instead.
 func SketchListerPageHandler(services *services.Services) func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
 	return func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *utils.PageData) {
 		// Override metadata fields specific to the sketch lister page
@@ -31,22 +44,57 @@ func SketchListerPageHandler(services *services.Services) func(w http.ResponseWr
 			return
 		}
 
-		sketchesData, err := services.Sketch.GetAllSketchesChronological()
-		if err != nil {
-			log.Printf("Error getting sketches from services: %v", err)
-			http.Error(w, "Failed to load sketches", http.StatusInternalServerError)
-			return
-		}
-
-		// Prepare the data for the template
 		templateData := SketchListerPageData{
 			PageData: *pageData,
-			Sketches: sketchesData,
+		}
+		if query := r.URL.Query(); query.Get("cursor") != "" {
+			query.Del("cursor")
+			firstPage := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+			templateData.FirstPageURL = firstPage.String()
+		}
+
+		searchReq, errMessage := parseSearchRequest(r.URL.Query())
+		if errMessage != "" {
+			templateData.ErrorMessage = errMessage
+		} else if searchReq.Query != "" {
+			templateData.SearchQuery = searchReq.Query
+			if searchReq.Limit == 0 {
+				searchReq.Limit = 100
+			}
+
+			results, err := services.Sketch.SearchSketches(searchReq)
+			if err != nil {
+				utils.LogRequestErrorf(r, "Error searching sketches for %q: %v", searchReq.Query, err)
+				http.Error(w, "Failed to load sketches", http.StatusInternalServerError)
+				return
+			}
+			for _, result := range results {
+				templateData.Sketches = append(templateData.Sketches, SketchListerItem{
+					SketchInfo: result,
+					// Snippets are HTML-escaped by the sketch service, apart from the <mark> highlights
+					SnippetHTML: template.HTML(result.Snippet),
+				})
+			}
+		} else if opts, err := parseListOptions(r.URL.Query()); err != nil {
+			templateData.ErrorMessage = err.Error()
+		} else {
+			sketchesData, nextCursor, err := services.Sketch.GetAllSketchesChronological(opts)
+			if err != nil && isListOptionsError(err) {
+				templateData.ErrorMessage = err.Error()
+			} else if err != nil {
+				utils.LogRequestErrorf(r, "Error getting sketches from services: %v", err)
+				http.Error(w, "Failed to load sketches", http.StatusInternalServerError)
+				return
+			}
+			for _, sketch := range sketchesData {
+				templateData.Sketches = append(templateData.Sketches, SketchListerItem{SketchInfo: sketch})
+			}
+			templateData.NextPageURL = nextPageURL(r, nextCursor)
 		}
 
-		err = tmpl.ExecuteTemplate(w, "page-sketch-lister", templateData)
+		err := tmpl.ExecuteTemplate(w, "page-sketch-lister", templateData)
 		if err != nil {
-			log.Printf("Error executing page-sketch-lister template: %v", err)
+			utils.LogRequestErrorf(r, "Error executing page-sketch-lister template: %v", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 	}
@@ -62,7 +62,7 @@ func SketchManagerPageHandler(services *services.Services) func(w http.ResponseW
 
 		err = tmpl.ExecuteTemplate(w, "page-sketch-manager", templateData)
 		if err != nil {
-			log.Printf("Error executing page-sketch-manager template: %v", err)
+			utils.LogRequestErrorf(r, "Error executing page-sketch-manager template: %v", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 	}
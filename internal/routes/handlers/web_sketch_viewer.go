@@ -1,17 +1,81 @@
 package handlers
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"html/template"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services"
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
 )
 
+// SketchLineage holds fork information shown on sketch pages.
+type SketchLineage struct {
+	ForkedFrom *model.SketchInfo  // Sketch this one was remixed from (if any)
+	Remixes    []model.SketchInfo // Sketches remixed from this one
+}
+
+// getSketchLineage loads the fork lineage of a sketch. Lookup errors are logged
+// and result in empty lineage so that the page still renders.
+func getSketchLineage(services *services.Services, sketch *model.Sketch) SketchLineage {
+	var lineage SketchLineage
+	if sketch.ID == 0 {
+		return lineage
+	}
+
+	if sketch.ForkedFromSketchID != nil {
+		forkedFrom, err := services.Sketch.GetSketchInfoByID(*sketch.ForkedFromSketchID)
+		if err != nil {
+			utils.LogErrorf("Error getting original of forked sketch %d: %v", sketch.ID, err)
+		} else {
+			lineage.ForkedFrom = forkedFrom
+		}
+	}
+
+	remixes, err := services.Sketch.GetRemixes(sketch.ID)
+	if err != nil {
+		utils.LogErrorf("Error getting remixes of sketch %d: %v", sketch.ID, err)
+	} else {
+		lineage.Remixes = remixes
+	}
+
+	return lineage
+}
+
+// renderSketchPage executes a sketch page template and answers 304 Not Modified when the
+// visitor already has the same page. The page also depends on the visitor (language, theme,
+// whether they are signed in) and on the sketch's remixes, so its ETag is a hash of the page
+// itself, and Last-Modified is only sent for information.
+func renderSketchPage(w http.ResponseWriter, r *http.Request, tmpl *template.Template, name string, data interface{}, sketch *model.Sketch) error {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// Pages differ between visitors, and must be revalidated so that edits show up at once
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Last-Modified", sketch.UpdatedAt.UTC().Format(http.TimeFormat))
+	if utils.CheckNotModified(w, r, etag, time.Time{}) {
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
 // SketchViewPageData holds all data for the clean sketch view template.
 type SketchViewPageData struct {
 	utils.PageData
+	SketchLineage
 	MemberName   string
 	SketchSlug   string
 	SketchJsPath string
@@ -47,6 +111,13 @@ func SketchViewerPageHandler(services *services.Services) func(w http.ResponseWr
 			return
 		}
 
+		// Private and hidden sketches are reported as missing to everyone but their owner
+		if !sketch.IsViewableBy(viewerMemberID(r, services)) {
+			log.Printf("Sketch %s/%s is private or hidden", memberName, sketchSlug)
+			http.Error(w, "Sketch not found", http.StatusNotFound)
+			return
+		}
+
 		// Use metadata from database for SEO
 		if sketch.Title != "" {
 			pageData.Title = sketch.Title
@@ -62,20 +133,21 @@ func SketchViewerPageHandler(services *services.Services) func(w http.ResponseWr
 		sketchJsPath := "/api/sketches/" + memberName + "/" + sketchSlug
 
 		templateData := SketchViewPageData{
-			PageData:     *pageData,
-			MemberName:   memberName,
-			SketchSlug:   sketchSlug,
-			SketchJsPath: sketchJsPath,
-			ExternalLibs: sketch.ExternalLibs,
-			Title:        sketch.Title,
+			PageData:      *pageData,
+			SketchLineage: getSketchLineage(services, sketch),
+			MemberName:    memberName,
+			SketchSlug:    sketchSlug,
+			SketchJsPath:  sketchJsPath,
+			ExternalLibs:  sketch.ExternalLibs,
+			Title:         sketch.Title,
 		}
 
 		log.Printf("Rendering clean sketch view for member: %s, sketch: %s (JS served from database)",
 			memberName, sketchSlug)
 
-		err = tmpl.ExecuteTemplate(w, "page-sketch-viewer", templateData)
+		err = renderSketchPage(w, r, tmpl, "page-sketch-viewer", templateData, sketch)
 		if err != nil {
-			log.Printf("Error executing page-sketch-viewer template: %v", err)
+			utils.LogRequestErrorf(r, "Error executing page-sketch-viewer template: %v", err)
 			http.Error(w, "Internal Server Error executing template", http.StatusInternalServerError)
 		}
 	}
@@ -0,0 +1,275 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/routes/handlers"
+	"github.com/sb-luis/creative-coding-bookclub/internal/services"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// quietRoutes are polled by probes and scrapers, so their requests are only logged at debug level
+var quietRoutes = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// requestLogMiddleware logs one line per request with its outcome, tagged with a request ID.
+// The ID is taken from the X-Request-ID header when a proxy sends one, and is sent back in it.
+func requestLogMiddleware(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		info := &utils.RequestInfo{ID: utils.GetOrCreateRequestID(r)}
+		w.Header().Set(utils.RequestIDHeader, info.ID)
+		r = utils.WithRequestInfo(r, info)
+
+		lw := &loggingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler(lw, r)
+
+		route := utils.RoutePattern(r)
+		level := slog.LevelInfo
+		switch {
+		case lw.status >= 500:
+			level = slog.LevelError
+		case quietRoutes[route]:
+			level = slog.LevelDebug
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_id", info.ID),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", route),
+			slog.Int("status", lw.status),
+			slog.Int64("bytes", lw.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("ip", utils.GetClientIP(r)),
+		}
+		if info.MemberID != 0 {
+			attrs = append(attrs, slog.Int("member_id", info.MemberID))
+		}
+		slog.LogAttrs(r.Context(), level, "request", attrs...)
+	}
+}
+
+// loggingResponseWriter records the status code and size of a response
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (lw *loggingResponseWriter) WriteHeader(status int) {
+	if !lw.wroteHeader {
+		lw.status = status
+		lw.wroteHeader = true
+	}
+	lw.ResponseWriter.WriteHeader(status)
+}
+
+func (lw *loggingResponseWriter) Write(body []byte) (int, error) {
+	lw.wroteHeader = true
+	n, err := lw.ResponseWriter.Write(body)
+	lw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (lw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
+// recoverMiddleware turns a panic in a handler into a logged stack trace and a 500 response:
+// JSON for API routes, the server error page otherwise. It must come after requestLogMiddleware
+// so that the request is still logged, with its request ID.
+func recoverMiddleware(templates *utils.Templates) utils.Middleware {
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			tw := &loggingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				// Used by net/http to abort a response on purpose; let the server handle it
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				utils.RequestLogger(r).Error("Panic while handling request",
+					"panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+
+				// Once the response has started, the client can only be left with a cut-off response
+				if tw.wroteHeader {
+					return
+				}
+				if isAPIPath(r.URL.Path) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write([]byte(`{"error":"Internal server error"}`))
+					return
+				}
+				handlers.ServerErrorHandler(w, r, templates.Get(), prepareBasicPageData(r, w))
+			}()
+
+			handler(tw, r)
+		}
+	}
+}
+
+// authMiddleware ensures that a request is authenticated
+func authMiddleware(services *services.Services) utils.Middleware {
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// Check authentication
+			sessionID, err := utils.GetSessionFromRequest(r)
+			if err != nil {
+				http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+				return
+			}
+
+			memberID, err := services.Session.GetMemberIDFromSession(sessionID)
+			if err != nil {
+				http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+				return
+			}
+
+			// Store authenticated member ID in request context
+			utils.SetRequestMemberID(r, memberID)
+			ctx := context.WithValue(r.Context(), "authenticated_member_id", memberID)
+			handler(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// csrfMiddleware rejects cross-site write requests. The request must not come from another site's
+// page (Origin/Referer check) and must send back the CSRF token rendered into our pages, either in
+// the X-CSRF-Token header or the csrf_token form field. Put it before authMiddleware.
+func csrfMiddleware(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.IsSameOriginRequest(r) {
+			log.Printf("Rejected cross-origin %s %s (origin %q, referer %q)", r.Method, r.URL.Path, r.Header.Get("Origin"), r.Header.Get("Referer"))
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"Cross-origin request rejected"}`, http.StatusForbidden)
+			return
+		}
+
+		if !utils.ValidCSRFToken(r) {
+			log.Printf("Rejected %s %s with a missing or invalid CSRF token", r.Method, r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"Invalid or missing CSRF token. Please reload the page and try again."}`, http.StatusForbidden)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// verifiedMiddleware blocks members still waiting in the approval queue.
+// It must come after authMiddleware, which sets the authenticated member ID.
+func verifiedMiddleware(services *services.Services) utils.Middleware {
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			memberID, ok := r.Context().Value("authenticated_member_id").(int)
+			if !ok {
+				http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+				return
+			}
+
+			member, err := services.Member.GetMemberByID(memberID)
+			if err != nil {
+				http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+				return
+			}
+			if !member.Verified {
+				w.Header().Set("Content-Type", "application/json")
+				http.Error(w, `{"error":"Your account is awaiting approval"}`, http.StatusForbidden)
+				return
+			}
+
+			handler(w, r)
+		}
+	}
+}
+
+// requireRole restricts handlers to members with the given role or a more privileged one.
+// It must come after authMiddleware, which sets the authenticated member ID.
+func requireRole(role string, services *services.Services) utils.Middleware {
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			memberID, ok := r.Context().Value("authenticated_member_id").(int)
+			if !ok {
+				http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+				return
+			}
+
+			member, err := services.Member.GetMemberByID(memberID)
+			if err != nil {
+				http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+				return
+			}
+			if member.Suspended || !member.HasRole(role) {
+				w.Header().Set("Content-Type", "application/json")
+				http.Error(w, `{"error":"You do not have permission to do this"}`, http.StatusForbidden)
+				return
+			}
+
+			handler(w, r)
+		}
+	}
+}
+
+// jsonErrorsMiddleware makes every error response of an API route JSON.
+// http.Error always sends text/plain, so error bodies that are already JSON
+// (`{"error":"..."}`) are relabelled, and plain messages are wrapped as {"error": message}.
+func jsonErrorsMiddleware(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(&jsonErrorWriter{ResponseWriter: w}, r)
+	}
+}
+
+// jsonErrorWriter rewrites plain text error responses as JSON
+type jsonErrorWriter struct {
+	http.ResponseWriter
+	rewrite bool // Set once a plain text error status has been written
+}
+
+func (jw *jsonErrorWriter) WriteHeader(status int) {
+	header := jw.Header()
+	if status >= 400 && strings.HasPrefix(header.Get("Content-Type"), "text/plain") {
+		jw.rewrite = true
+		header.Set("Content-Type", "application/json")
+	}
+	jw.ResponseWriter.WriteHeader(status)
+}
+
+func (jw *jsonErrorWriter) Write(body []byte) (int, error) {
+	if !jw.rewrite {
+		return jw.ResponseWriter.Write(body)
+	}
+
+	message := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(message, "{") {
+		encoded, err := json.Marshal(map[string]string{"error": message})
+		if err != nil {
+			return 0, err
+		}
+		if _, err := jw.ResponseWriter.Write(encoded); err != nil {
+			return 0, err
+		}
+		return len(body), nil
+	}
+	return jw.ResponseWriter.Write(body)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (jw *jsonErrorWriter) Unwrap() http.ResponseWriter {
+	return jw.ResponseWriter
+}
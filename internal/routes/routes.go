@@ -1,13 +1,13 @@
 package routes
 
 import (
-	"context"
 	"html/template"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strings"
 
+	"github.com/sb-luis/creative-coding-bookclub/internal/metrics"
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
 	"github.com/sb-luis/creative-coding-bookclub/internal/routes/handlers"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services"
 	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
@@ -20,13 +20,16 @@ func preparePageData(r *http.Request, w http.ResponseWriter, currentLang string,
 
 	pageData := utils.GetDefaultPageData(r.URL.Path, currentLang, theme, r.RequestURI)
 	pageData.SupportedLanguages = utils.GetSupportedLanguages()
+	pageData.CSRFToken = utils.GetOrCreateCSRFToken(w, r)
 
 	// Check authentication status
 	if sessionID, err := utils.GetSessionFromRequest(r); err == nil {
 		if memberID, err := services.Session.GetMemberIDFromSession(sessionID); err == nil {
 			if member, err := services.Member.GetMemberByID(memberID); err == nil {
 				pageData.IsAuthenticated = true
+				utils.SetRequestMemberID(r, memberID)
 				pageData.MemberName = member.Name
+				pageData.CanModerate = member.HasRole(model.RoleModerator)
 			} else {
 				pageData.IsAuthenticated = false
 				pageData.MemberName = ""
@@ -43,253 +46,218 @@ func preparePageData(r *http.Request, w http.ResponseWriter, currentLang string,
 	return pageData
 }
 
-// authMiddleware ensures that a request is authenticated
-func authMiddleware(handler http.HandlerFunc, services *services.Services) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Check authentication
-		sessionID, err := utils.GetSessionFromRequest(r)
-		if err != nil {
-			http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
-			return
-		}
+// renderNotFound renders the custom 404 page.
+func renderNotFound(w http.ResponseWriter, r *http.Request, masterTmpl *template.Template, pageData *utils.PageData) {
+	handlers.NotFoundHandler(w, r, masterTmpl, pageData)
+}
 
-		memberID, err := services.Session.GetMemberIDFromSession(sessionID)
-		if err != nil {
-			http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
-			return
-		}
+// prepareBasicPageData prepares page data without looking up the member, for error pages
+// that must render even when the database is unavailable
+func prepareBasicPageData(r *http.Request, w http.ResponseWriter) *utils.PageData {
+	currentLang := utils.GetCurrentLanguage(r)
+	theme := utils.GetResolvedTheme(r)
+	utils.SetThemeCookie(w, theme) // Ensure cookie is set
 
-		// Store authenticated member ID in request context
-		ctx := context.WithValue(r.Context(), "authenticated_member_id", memberID)
-		handler(w, r.WithContext(ctx))
-	}
+	pageData := utils.GetDefaultPageData(r.URL.Path, currentLang, theme, r.RequestURI)
+	pageData.SupportedLanguages = utils.GetSupportedLanguages()
+	return pageData
 }
 
-// renderNotFound renders the custom 404 page.
-func renderNotFound(w http.ResponseWriter, r *http.Request, masterTmpl *template.Template, pageData *utils.PageData) {
-	handlers.NotFoundHandler(w, r, masterTmpl, pageData)
+// isAPIPath reports whether a request path belongs to the JSON API
+func isAPIPath(path string) bool {
+	return path == "/api" || strings.HasPrefix(path, "/api/")
 }
 
 // RegisterRoutes registers all the route handlers to the provided custom Router.
 func RegisterRoutes(router *utils.Router, services *services.Services) {
-	baseDir, err := os.Getwd()
-	if err != nil {
-		log.Fatalf("Error getting working directory: %v", err)
-	}
-
-	htmlTemplates, err := utils.GetTemplateFiles()
+	// Hash the static assets first, as templates link to them by their fingerprinted URLs
+	assets, err := utils.LoadAssets()
 	if err != nil {
-		log.Fatalf("Error retrieving template files: %v", err)
+		log.Fatalf("Error loading static assets: %v", err)
 	}
 
-	masterTmpl, err := template.New("").Funcs(template.FuncMap{
+	templates, err := utils.LoadTemplates(template.FuncMap{
+		"asset":    assets.URL,
 		"i18nText": utils.Translate,
 		"i18nHtml": func(lang string, key string, args ...interface{}) template.HTML {
 			translatedText := utils.Translate(lang, key, args...)
 			return template.HTML(utils.InnerMarkToHTML(translatedText))
 		},
-	}).ParseFiles(htmlTemplates...)
+	})
 	if err != nil {
 		log.Fatalf("Error parsing templates: %v", err)
 	}
 
-	// Serve static files from "web/assets" under "/assets/"
-	staticAssetsDir := filepath.Join(baseDir, "web/assets")
+	// Serve static files from the web assets under "/assets/"
+	router.PathPrefix("/assets/", http.StripPrefix("/assets", assets))
+
+	// Count requests and their latency per route pattern for /metrics
+	router.Observe = metrics.ObserveRequest
+
+	// Log every request, and answer with a 500 instead of dropping the connection when a handler panics
+	router.Use(requestLogMiddleware, recoverMiddleware(templates))
+
+	// Route protection, from least to most restrictive
+	auth := authMiddleware(services)
+	verified := verifiedMiddleware(services)
+	moderator := requireRole(model.RoleModerator, services)
+	admin := requireRole(model.RoleAdmin, services)
+
+	// page adapts a page handler: it gets a clone of the master template and the common page data
+	page := func(handler utils.PageHandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			currentLang := utils.GetCurrentLanguage(r)
+			pageData := preparePageData(r, w, currentLang, services)
+			tmpl, err := templates.Get().Clone()
+			if err != nil {
+				utils.LogRequestErrorf(r, "Error cloning master template for %s: %v", r.URL.Path, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			handler(w, r, tmpl, pageData)
+		}
+	}
+
+	// =============================================================================
+	// HEALTH AND MONITORING ROUTES - Container probes and Prometheus scraping
+	// =============================================================================
 
-	router.PathPrefix("/assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir(staticAssetsDir))))
+	router.HandleFunc("/healthz", handlers.HealthzHandler, "GET")
+	router.HandleFunc("/readyz", handlers.ReadyzHandler, "GET")
+	router.HandleFunc("/metrics", handlers.MetricsHandler(services), "GET") // bearer token from METRICS_TOKEN, if set
 
 	// =============================================================================
 	// API ROUTES - Backend data endpoints
 	// =============================================================================
 
+	api := router.Group("/api", jsonErrorsMiddleware)
+	apiMember := api.With(auth)
+	// Writes are CSRF protected, as browsers send the session cookie along with them
+	apiWrite := api.With(csrfMiddleware, auth)
+	apiVerifiedWrite := apiWrite.With(verified)
+
 	// Public Member API endpoints
-	router.HandleFunc("/api/members", handlers.GetMembersHandler(services), "GET")
-	router.HandleFunc("/api/members/me", authMiddleware(handlers.GetCurrentMemberHandler(services), services), "GET")
-	router.HandleFunc("/api/members/me", authMiddleware(handlers.UpdatePasswordHandler(services), services), "PATCH")
-
-	// Public Preference API endpoints
-	router.HandleFunc("/api/preferences/theme", handlers.ThemePreferencesPostHandler, "POST")
-	router.HandleFunc("/api/preferences/locale", handlers.LocalePreferencesPostHandler, "POST")
-
-	// Public Authentication API endpoints
-	router.HandleFunc("/api/auth/register", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for register: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.RegisterPostHandler(services)(w, r, tmpl, pageData)
-	}, "POST")
-	router.HandleFunc("/api/auth/sign-in", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for sign-in: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.SignInPostHandler(services)(w, r, tmpl, pageData)
-	}, "POST")
+	api.HandleFunc("/members", handlers.GetMembersHandler(services), "GET")
+	apiMember.HandleFunc("/members/me", handlers.GetCurrentMemberHandler(services), "GET")
+	apiWrite.HandleFunc("/members/me", handlers.UpdatePasswordHandler(services), "PATCH")
+	apiWrite.HandleFunc("/members/me/profile", handlers.UpdateProfileHandler(services), "PUT")
+	apiMember.HandleFunc("/members/me/sessions", handlers.GetSessionsHandler(services), "GET")
+	apiWrite.HandleFunc("/members/me/sessions", handlers.DeleteAllSessionsHandler(services), "DELETE") // sign out everywhere
+	apiWrite.HandleFunc("/members/me/sessions/{sessionID}", handlers.DeleteSessionHandler(services), "DELETE")
+
+	// Public Preference API endpoints (these only set display cookies, so they are not CSRF protected)
+	api.HandleFunc("/preferences/theme", handlers.ThemePreferencesPostHandler, "POST")
+	api.HandleFunc("/preferences/locale", handlers.LocalePreferencesPostHandler, "POST")
+
+	// Public Authentication API endpoints (HTML form posts that render the form again on errors)
+	authForms := router.Group("/api/auth", csrfMiddleware)
+	authForms.HandleFunc("/register", page(handlers.RegisterPostHandler(services)), "POST")
+	authForms.HandleFunc("/sign-in", page(handlers.SignInPostHandler(services)), "POST")
 
 	// Protected Authentication API endpoints
-	router.HandleFunc("/api/auth/sign-out", authMiddleware(handlers.SignOutHandler(services), services), "POST")
+	apiWrite.HandleFunc("/auth/sign-out", handlers.SignOutHandler(services), "POST")
 
 	// Public Sketch API endpoints
-	router.HandleFunc("/api/sketches/{memberName}", handlers.GetMemberSketchesHandler(services), "GET")
-	router.HandleFunc("/api/sketches/{memberName}/{sketchSlug}", handlers.SketchCodeHandler(services), "GET")
-
-	// Protected Sketch API endpoints (require authentication)
-	router.HandleFunc("/api/sketches/{memberName}/{sketchSlug}", authMiddleware(handlers.CreateSketchHandler(services), services), "POST")
-	router.HandleFunc("/api/sketches/{memberName}/{sketchSlug}", authMiddleware(handlers.UpdateSketchHandler(services), services), "PUT")           // source code only
-	router.HandleFunc("/api/sketches/{memberName}/{sketchSlug}", authMiddleware(handlers.UpdateSketchMetadataHandler(services), services), "PATCH") // metadata only
-	router.HandleFunc("/api/sketches/{memberName}/{sketchSlug}", authMiddleware(handlers.DeleteSketchHandler(services), services), "DELETE")
+	api.HandleFunc("/sketches", handlers.GetSketchesHandler(services), "GET")                    // ?limit=&cursor=&sort=&tag=&lib=
+	api.HandleFunc("/sketches/{memberName}", handlers.GetMemberSketchesHandler(services), "GET") // ?limit=&cursor=&sort=&tag=&lib=
+	api.HandleFunc("/sketches/{memberName}/{sketchSlug}", handlers.SketchCodeHandler(services), "GET")
+
+	// Public Search API endpoint (?q=&member=&tag=&lib=&from=&to=&code=&limit=)
+	api.HandleFunc("/search", handlers.SearchSketchesHandler(services), "GET")
+
+	// Protected Sketch API endpoints (require authentication and an approved account)
+	apiVerifiedWrite.HandleFunc("/sketches/{memberName}/{sketchSlug}", handlers.CreateSketchHandler(services), "POST")          // ?strict=1 refuses code with syntax errors
+	apiVerifiedWrite.HandleFunc("/sketches/{memberName}/{sketchSlug}", handlers.UpdateSketchHandler(services), "PUT")           // source code only, ?strict=1 refuses code with syntax errors
+	apiVerifiedWrite.HandleFunc("/sketches/{memberName}/{sketchSlug}", handlers.UpdateSketchMetadataHandler(services), "PATCH") // metadata only
+	apiVerifiedWrite.HandleFunc("/sketches/{memberName}/{sketchSlug}", handlers.DeleteSketchHandler(services), "DELETE")
+
+	// Fork (remix) a sketch into the authenticated member's account
+	apiVerifiedWrite.HandleFunc("/sketches/{memberName}/{sketchSlug}/fork", handlers.ForkSketchHandler(services), "POST")
+
+	// Sketch revision history endpoints
+	api.HandleFunc("/sketches/{memberName}/{sketchSlug}/revisions", handlers.GetSketchRevisionsHandler(services), "GET")
+	api.HandleFunc("/sketches/{memberName}/{sketchSlug}/revisions/{revision}", handlers.GetSketchRevisionHandler(services), "GET")
+	api.HandleFunc("/sketches/{memberName}/{sketchSlug}/diff", handlers.GetSketchRevisionDiffHandler(services), "GET") // ?from=&to=
+	apiVerifiedWrite.HandleFunc("/sketches/{memberName}/{sketchSlug}/revisions/{revision}/restore", handlers.RestoreSketchRevisionHandler(services), "POST")
+
+	// Tools for the sketch editor (members only, as formatting costs CPU; signed-out visitors format in the browser)
+	apiWrite.HandleFunc("/tools/format", handlers.FormatCodeHandler, "POST")
+
+	// Admin API endpoints (require the moderator role; role changes and password resets require admin)
+	apiMember.Group("/admin", moderator).HandleFunc("/stats", handlers.GetAdminStatsHandler(services), "GET")
+	adminWrite := apiWrite.Group("/admin", moderator)
+	adminWrite.HandleFunc("/members/{memberName}", handlers.AdminUpdateMemberHandler(services), "PATCH") // verified, suspended, role
+	adminWrite.With(admin).HandleFunc("/members/{memberName}/password-reset", handlers.AdminResetPasswordHandler(services), "POST")
+	adminWrite.HandleFunc("/sketches/{memberName}/{sketchSlug}", handlers.AdminHideSketchHandler(services), "PATCH") // hidden
+	adminWrite.HandleFunc("/sketches/{memberName}/{sketchSlug}", handlers.AdminDeleteSketchHandler(services), "DELETE")
 
 	// =============================================================================
 	// WEB ROUTES - Frontend HTML page rendering
 	// =============================================================================
 
-	// Register page
-	router.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for register: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.RegisterGetHandler(w, r, tmpl, pageData)
-	}, "GET")
-
-	// Sign-in page
-	router.HandleFunc("/sign-in", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for sign-in: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.SignInGetHandler(w, r, tmpl, pageData)
-	}, "GET")
+	// Register and sign-in pages
+	router.HandleFunc("/register", page(handlers.RegisterGetHandler), "GET")
+	router.HandleFunc("/sign-in", page(handlers.SignInGetHandler), "GET")
 
 	// Member's profile (requires authentication)
-	router.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for me: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.ProfileHandler(services)(w, r, tmpl, pageData)
-	}, "GET")
+	router.HandleFunc("/me", page(handlers.ProfileHandler(services)), "GET")
+
+	// Member's public profile page
+	router.HandleFunc("/members/{memberName}", page(handlers.MemberProfilePageHandler(services)), "GET")
+
+	// Old sketch URLs redirect to /sketches/{memberName}/{sketchSlug}
+	router.HandleFunc("/members/{memberName}/{sketchSlug}", handlers.MemberSketchRedirectHandler, "GET")
 
 	// Clean sketch view page (for viewing only, no editor)
-	router.HandleFunc("/sketches/{memberName}/{sketchSlug}", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for sketch view: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.SketchViewerPageHandler(services)(w, r, tmpl, pageData)
-	}, "GET")
+	router.HandleFunc("/sketches/{memberName}/{sketchSlug}", page(handlers.SketchViewerPageHandler(services)), "GET")
 
 	// Sketch iframe content (for sandboxed execution)
-	router.HandleFunc("/sketches/{memberName}/{sketchSlug}/iframe", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for sketch iframe: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.SketchIframeContentHandler(services)(w, r, tmpl, pageData)
-	}, "GET")
+	router.HandleFunc("/sketches/{memberName}/{sketchSlug}/iframe", page(handlers.SketchIframeContentHandler(services)), "GET")
 
 	// Member's sketch page (with editor capabilities)
-	router.HandleFunc("/sketches/{memberName}/{sketchSlug}/edit", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for sketch page: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.SketchEditorPageHandler(services)(w, r, tmpl, pageData)
-	}, "GET")
+	router.HandleFunc("/sketches/{memberName}/{sketchSlug}/edit", page(handlers.SketchEditorPageHandler(services)), "GET")
 
 	// Sketch lister page
-	router.HandleFunc("/sketches", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for sketch lister: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.SketchListerPageHandler(services)(w, r, tmpl, pageData)
-	}, "GET")
+	router.HandleFunc("/sketches", page(handlers.SketchListerPageHandler(services)), "GET")
 
 	// Sketch Manager page (requires authentication)
-	router.HandleFunc("/sketch-manager", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for sketch manager: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.SketchManagerPageHandler(services)(w, r, tmpl, pageData)
-	}, "GET")
+	router.HandleFunc("/sketch-manager", page(handlers.SketchManagerPageHandler(services)), "GET")
+
+	// Admin pages (require the moderator role)
+	adminPages := router.Group("/admin", auth, moderator)
+	adminPages.HandleFunc("", page(handlers.AdminDashboardPageHandler(services)), "GET")
+	adminPages.HandleFunc("/members", page(handlers.AdminMembersPageHandler(services)), "GET")
+	adminPages.HandleFunc("/sketches", page(handlers.AdminSketchesPageHandler(services)), "GET")
 
 	// Empty iframe page for iframe initialization
-	router.HandleFunc("/empty-iframe", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning master template for empty iframe: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		handlers.EmptyIframeHandler(w, r, tmpl, pageData)
-	}, "GET")
+	router.HandleFunc("/empty-iframe", page(handlers.EmptyIframeHandler), "GET")
 
 	// Homepage
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		pageData := preparePageData(r, w, currentLang, services)
-		tmpl, err := masterTmpl.Clone()
-		if err != nil {
-			log.Printf("Error cloning template for /: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	router.HandleFunc("/", page(handlers.HomePageGetHandler), "GET")
+
+	// Let other sites read the public API when CORS_ALLOWED_ORIGINS is set
+	router.CORSAllowedOrigins = utils.GetCORSAllowedOrigins()
+
+	// API clients get JSON errors for unknown paths and methods, browsers get the 404 page
+	router.MethodNotAllowedHandler = func(w http.ResponseWriter, r *http.Request) {
+		if isAPIPath(r.URL.Path) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(`{"error":"Method not allowed"}`))
 			return
 		}
-		handlers.HomePageGetHandler(w, r, tmpl, pageData)
-	}, "GET")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
 
 	// Set the NotFoundHandler on the router
 	router.NotFoundHandler = func(w http.ResponseWriter, r *http.Request) {
-		currentLang := utils.GetCurrentLanguage(r)
-		theme := utils.GetResolvedTheme(r)
-		utils.SetThemeCookie(w, theme) // Ensure cookie is set
+		if isAPIPath(r.URL.Path) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"Not found"}`))
+			return
+		}
 
-		pageData := utils.GetDefaultPageData(r.URL.Path, currentLang, theme, r.RequestURI)
-		pageData.SupportedLanguages = utils.GetSupportedLanguages()
-		renderNotFound(w, r, masterTmpl, pageData)
+		renderNotFound(w, r, templates.Get(), prepareBasicPageData(r, w))
 	}
 }
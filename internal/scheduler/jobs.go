@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/services"
+)
+
+// RegisterHousekeepingJobs registers the jobs that keep the database tidy.
+// New periodic jobs (digest emails, stale-draft pruning, stats rollups) belong here too.
+func RegisterHousekeepingJobs(s *Scheduler, services *services.Services) error {
+	jobs := []Job{
+		{
+			Name:     "cleanup-expired-sessions",
+			Interval: time.Hour,
+			Timeout:  time.Minute,
+			Run:      services.Session.CleanupExpiredSessions,
+		},
+		{
+			Name:     "cleanup-sign-in-attempts",
+			Interval: time.Hour,
+			Timeout:  time.Minute,
+			Run:      services.SignIn.CleanupOldAttempts,
+		},
+	}
+
+	for _, job := range jobs {
+		if err := s.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
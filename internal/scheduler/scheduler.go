@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// Job is a named task run periodically in the background
+type Job struct {
+	Name     string
+	Interval time.Duration                   // Time between runs, before jitter
+	Timeout  time.Duration                   // The run's context is cancelled after this long
+	Run      func(ctx context.Context) error // Should stop early when ctx is done
+}
+
+// jitterFraction is how far each wait may stray from the job interval, so that jobs registered
+// with the same interval, or on several instances started together, do not all run at once
+const jitterFraction = 0.1
+
+// Scheduler runs registered jobs in the background until it is stopped.
+// Each run takes a Postgres advisory lock named after the job, so that when several instances
+// of the server share a database, only one of them runs a given job at a time.
+type Scheduler struct {
+	db     *sql.DB
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a scheduler that takes its locks on the given database
+func New(db *sql.DB) *Scheduler {
+	return &Scheduler{db: db}
+}
+
+// Register adds a job to the scheduler. Jobs must be registered before Start is called.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" {
+		return errors.New("job name cannot be empty")
+	}
+	if job.Interval <= 0 {
+		return fmt.Errorf("job %s must have a positive interval", job.Name)
+	}
+	if job.Run == nil {
+		return fmt.Errorf("job %s has no run function", job.Name)
+	}
+	for _, registered := range s.jobs {
+		if registered.Name == job.Name {
+			return fmt.Errorf("job %s is already registered", job.Name)
+		}
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = job.Interval
+	}
+
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// Start runs every registered job in its own goroutine. The first run of each job happens
+// after a random part of the jitter, not right away, to spread the load when the server starts.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+
+	log.Printf("Scheduler started with %d jobs", len(s.jobs))
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	log.Printf("Scheduler stopped")
+}
+
+// loop runs a job every interval until ctx is cancelled
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	wait := time.Duration(rand.Float64() * jitterFraction * float64(job.Interval))
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.runOnce(ctx, job)
+
+		// Wait the interval give or take the jitter
+		jitter := (rand.Float64()*2 - 1) * jitterFraction * float64(job.Interval)
+		wait = job.Interval + time.Duration(jitter)
+	}
+}
+
+// runOnce runs a job if no other instance holds its lock, and logs the result
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	ctx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+
+	// Advisory locks belong to a database session, so the lock is taken and released on a
+	// dedicated connection that is held for the whole run
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		utils.LogErrorf("Job %s: could not get a database connection: %v", job.Name, err)
+		return
+	}
+	defer conn.Close()
+
+	key := lockKey(job.Name)
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		utils.LogErrorf("Job %s: could not take lock: %v", job.Name, err)
+		return
+	}
+	if !locked {
+		log.Printf("Job %s: skipped, already running on another instance", job.Name)
+		return
+	}
+	defer func() {
+		// The run's context may be done by now, but the lock must still be released
+		// before the connection goes back to the pool
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			utils.LogErrorf("Job %s: could not release lock: %v", job.Name, err)
+		}
+	}()
+
+	start := time.Now()
+	err = runRecovered(ctx, job)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		utils.LogErrorf("Job %s failed after %s: %v", job.Name, elapsed, err)
+		return
+	}
+	log.Printf("Job %s finished in %s", job.Name, elapsed)
+}
+
+// runRecovered runs a job, turning a panic into an error so that one bad run does not stop the server
+func runRecovered(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// lockKey derives the advisory lock key of a job from its name
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("scheduler:" + name))
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// Service handles queries for the admin dashboard that span several tables
+type Service struct {
+	db *sql.DB
+}
+
+// NewService creates a new admin service
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// GetStats returns the counts shown on the admin dashboard
+func (s *Service) GetStats() (*model.AdminStats, error) {
+	now := time.Now()
+	stats := &model.AdminStats{}
+	err := s.db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM members),
+			(SELECT COUNT(*) FROM members WHERE NOT verified),
+			(SELECT COUNT(*) FROM members WHERE suspended),
+			(SELECT COUNT(*) FROM sketches),
+			(SELECT COUNT(*) FROM sketches WHERE visibility = 'public' AND NOT hidden),
+			(SELECT COUNT(*) FROM sketches WHERE hidden),
+			(SELECT COUNT(*) FROM sketches WHERE created_at >= $1),
+			(SELECT COUNT(*) FROM sessions WHERE expires_at > $2),
+			(SELECT COUNT(*) FROM invite_codes WHERE uses < max_uses AND (expires_at IS NULL OR expires_at > $2))`,
+		now.AddDate(0, 0, -7), now).Scan(
+		&stats.Members, &stats.UnverifiedMembers, &stats.SuspendedMembers,
+		&stats.Sketches, &stats.PublicSketches, &stats.HiddenSketches, &stats.SketchesThisWeek,
+		&stats.ActiveSessions, &stats.OpenInviteCodes)
+	if err != nil {
+		utils.LogErrorf("Database error while getting admin stats: %v", err)
+		return nil, fmt.Errorf("failed to get admin stats: %w", err)
+	}
+
+	return stats, nil
+}
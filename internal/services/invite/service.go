@@ -0,0 +1,138 @@
+package invite
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// Service handles invite code business logic
+type Service struct {
+	db *sql.DB
+}
+
+// NewService creates a new invite service
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// generateCode creates a random, easy to type invite code (e.g. "k3jd-7qpa-mx2e")
+func generateCode() (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(bytes))[:12]
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12], nil
+}
+
+// CreateInviteCode creates a new invite code. createdBy is the organiser's member ID, or 0 if created from the command line.
+func (s *Service) CreateInviteCode(createdBy int, req *model.CreateInviteCodeRequest) (*model.InviteCode, error) {
+	if req.MaxUses <= 0 {
+		return nil, errors.New("max uses must be positive")
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invite code: %w", err)
+	}
+
+	var presetName, creator interface{}
+	if req.PresetName != "" {
+		presetName = req.PresetName
+	}
+	if createdBy > 0 {
+		creator = createdBy
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO invite_codes (code, preset_name, max_uses, uses, expires_at, created_by, created_at)
+		VALUES ($1, $2, $3, 0, $4, $5, $6)`,
+		code, presetName, req.MaxUses, req.ExpiresAt, creator, time.Now())
+	if err != nil {
+		utils.LogErrorf("Database error while creating invite code: %v", err)
+		return nil, fmt.Errorf("failed to create invite code: %w", err)
+	}
+
+	return s.GetInviteCode(code)
+}
+
+// GetInviteCode returns an invite code
+func (s *Service) GetInviteCode(code string) (*model.InviteCode, error) {
+	if code == "" {
+		return nil, errors.New("invite code cannot be empty")
+	}
+
+	invite := &model.InviteCode{}
+	err := s.db.QueryRow(`
+		SELECT code, preset_name, max_uses, uses, expires_at, created_by, created_at
+		FROM invite_codes WHERE code = $1`, code).Scan(
+		&invite.Code, &invite.PresetName, &invite.MaxUses, &invite.Uses,
+		&invite.ExpiresAt, &invite.CreatedBy, &invite.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invite code not found")
+	}
+	if err != nil {
+		utils.LogErrorf("Database error while getting invite code: %v", err)
+		return nil, fmt.Errorf("failed to get invite code: %w", err)
+	}
+
+	return invite, nil
+}
+
+// GetAllInviteCodes returns all invite codes, newest first
+func (s *Service) GetAllInviteCodes() ([]model.InviteCode, error) {
+	rows, err := s.db.Query(`
+		SELECT code, preset_name, max_uses, uses, expires_at, created_by, created_at
+		FROM invite_codes ORDER BY created_at DESC`)
+	if err != nil {
+		utils.LogErrorf("Database error while getting all invite codes: %v", err)
+		return nil, fmt.Errorf("failed to get all invite codes: %w", err)
+	}
+	defer rows.Close()
+
+	var invites []model.InviteCode
+	for rows.Next() {
+		invite := model.InviteCode{}
+		err := rows.Scan(
+			&invite.Code, &invite.PresetName, &invite.MaxUses, &invite.Uses,
+			&invite.ExpiresAt, &invite.CreatedBy, &invite.CreatedAt)
+		if err != nil {
+			utils.LogErrorf("Database error while scanning invite code: %v", err)
+			continue
+		}
+		invites = append(invites, invite)
+	}
+
+	return invites, nil
+}
+
+// DeleteInviteCode revokes an invite code. Members who already used it stay verified.
+func (s *Service) DeleteInviteCode(code string) error {
+	result, err := s.db.Exec("DELETE FROM invite_codes WHERE code = $1", code)
+	if err != nil {
+		utils.LogErrorf("Database error while deleting invite code: %v", err)
+		return fmt.Errorf("failed to delete invite code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errors.New("invite code not found")
+	}
+
+	return nil
+}
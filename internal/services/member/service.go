@@ -2,12 +2,13 @@ package member
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
 )
 
 // Service handles member-related business logic
@@ -20,7 +21,8 @@ func NewService(db *sql.DB) *Service {
 	return &Service{db: db}
 }
 
-// CreateMember creates a new member with validation (password should be pre-hashed)
+// CreateMember creates a new unverified member (password should be pre-hashed).
+// Unverified members wait in the approval queue until VerifyMember is called.
 func (s *Service) CreateMember(name, passwordHash string) (*model.Member, error) {
 	if name == "" {
 		return nil, errors.New("name cannot be empty")
@@ -30,13 +32,11 @@ func (s *Service) CreateMember(name, passwordHash string) (*model.Member, error)
 	}
 
 	// Check if name already exists
-	var count int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM members WHERE name = $1", name).Scan(&count)
+	exists, err := s.memberNameExists(name)
 	if err != nil {
-		log.Printf("Database error while checking if member exists for name '%s': %v", name, err)
-		return nil, fmt.Errorf("failed to check if member exists: %w", err)
+		return nil, err
 	}
-	if count > 0 {
+	if exists {
 		return nil, errors.New("name already exists")
 	}
 
@@ -45,9 +45,9 @@ func (s *Service) CreateMember(name, passwordHash string) (*model.Member, error)
 	err = s.db.QueryRow(`
 		INSERT INTO members (name, password_hash, verified, created_at, updated_at) 
 		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
-		name, passwordHash, true, time.Now(), time.Now()).Scan(&id)
+		name, passwordHash, false, time.Now(), time.Now()).Scan(&id)
 	if err != nil {
-		log.Printf("Database error while creating member '%s': %v", name, err)
+		utils.LogErrorf("Database error while creating member '%s': %v", name, err)
 		return nil, fmt.Errorf("failed to create member: %w", err)
 	}
 
@@ -55,6 +55,92 @@ func (s *Service) CreateMember(name, passwordHash string) (*model.Member, error)
 	return s.GetMemberByID(id)
 }
 
+// CreateMemberWithInviteCode redeems an invite code and creates a verified member in one transaction.
+// Codes with a preset name can only be used to register that name.
+func (s *Service) CreateMemberWithInviteCode(name, passwordHash, inviteCode string) (*model.Member, error) {
+	if name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	if passwordHash == "" {
+		return nil, errors.New("password hash cannot be empty")
+	}
+	if inviteCode == "" {
+		return nil, errors.New("invalid invite code")
+	}
+
+	exists, err := s.memberNameExists(name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New("name already exists")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the code so that concurrent registrations cannot exceed its use limit
+	var presetName *string
+	var maxUses, uses int
+	var expiresAt *time.Time
+	err = tx.QueryRow(`
+		SELECT preset_name, max_uses, uses, expires_at 
+		FROM invite_codes WHERE code = $1 FOR UPDATE`, inviteCode).Scan(&presetName, &maxUses, &uses, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invalid invite code")
+	}
+	if err != nil {
+		utils.LogErrorf("Database error while getting invite code: %v", err)
+		return nil, fmt.Errorf("failed to get invite code: %w", err)
+	}
+
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, errors.New("invite code expired")
+	}
+	if uses >= maxUses {
+		return nil, errors.New("invite code used up")
+	}
+	if presetName != nil && *presetName != name {
+		return nil, errors.New("invite code reserved for another name")
+	}
+
+	if _, err := tx.Exec("UPDATE invite_codes SET uses = uses + 1 WHERE code = $1", inviteCode); err != nil {
+		utils.LogErrorf("Database error while redeeming invite code: %v", err)
+		return nil, fmt.Errorf("failed to redeem invite code: %w", err)
+	}
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO members (name, password_hash, verified, invite_code, created_at, updated_at) 
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		name, passwordHash, true, inviteCode, now, now).Scan(&id)
+	if err != nil {
+		utils.LogErrorf("Database error while creating member '%s': %v", name, err)
+		return nil, fmt.Errorf("failed to create member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetMemberByID(id)
+}
+
+// memberNameExists checks if a member name is already taken
+func (s *Service) memberNameExists(name string) (bool, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM members WHERE name = $1", name).Scan(&count)
+	if err != nil {
+		utils.LogErrorf("Database error while checking if member exists for name '%s': %v", name, err)
+		return false, fmt.Errorf("failed to check if member exists: %w", err)
+	}
+	return count > 0, nil
+}
+
 // GetMemberByName returns a member by name
 func (s *Service) GetMemberByName(name string) (*model.Member, error) {
 	if name == "" {
@@ -63,18 +149,19 @@ func (s *Service) GetMemberByName(name string) (*model.Member, error) {
 
 	member := &model.Member{}
 	err := s.db.QueryRow(`
-		SELECT id, name, password_hash, verified, created_at, updated_at 
+		SELECT id, name, password_hash, verified, role, suspended, bio, avatar_url, links, created_at, updated_at 
 		FROM members WHERE name = $1`, name).Scan(
-		&member.ID, &member.Name, &member.PasswordHash, &member.Verified,
-		&member.CreatedAt, &member.UpdatedAt)
+		&member.ID, &member.Name, &member.PasswordHash, &member.Verified, &member.Role, &member.Suspended,
+		&member.Bio, &member.AvatarURL, &member.LinksJSON, &member.CreatedAt, &member.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("member not found")
 	}
 	if err != nil {
-		log.Printf("Database error while getting member by name '%s': %v", name, err)
+		utils.LogErrorf("Database error while getting member by name '%s': %v", name, err)
 		return nil, fmt.Errorf("failed to get member by name: %w", err)
 	}
+	unmarshalLinks(member)
 
 	return member, nil
 }
@@ -87,18 +174,19 @@ func (s *Service) GetMemberByID(id int) (*model.Member, error) {
 
 	member := &model.Member{}
 	err := s.db.QueryRow(`
-		SELECT id, name, password_hash, verified, created_at, updated_at 
+		SELECT id, name, password_hash, verified, role, suspended, bio, avatar_url, links, created_at, updated_at 
 		FROM members WHERE id = $1`, id).Scan(
-		&member.ID, &member.Name, &member.PasswordHash, &member.Verified,
-		&member.CreatedAt, &member.UpdatedAt)
+		&member.ID, &member.Name, &member.PasswordHash, &member.Verified, &member.Role, &member.Suspended,
+		&member.Bio, &member.AvatarURL, &member.LinksJSON, &member.CreatedAt, &member.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("member not found")
 	}
 	if err != nil {
-		log.Printf("Database error while getting member by ID %d: %v", id, err)
+		utils.LogErrorf("Database error while getting member by ID %d: %v", id, err)
 		return nil, fmt.Errorf("failed to get member by ID: %w", err)
 	}
+	unmarshalLinks(member)
 
 	return member, nil
 }
@@ -106,10 +194,10 @@ func (s *Service) GetMemberByID(id int) (*model.Member, error) {
 // GetAllMembers returns all members
 func (s *Service) GetAllMembers() ([]model.Member, error) {
 	rows, err := s.db.Query(`
-		SELECT id, name, verified, created_at, updated_at 
+		SELECT id, name, verified, role, suspended, created_at, updated_at 
 		FROM members ORDER BY name ASC`)
 	if err != nil {
-		log.Printf("Database error while getting all members: %v", err)
+		utils.LogErrorf("Database error while getting all members: %v", err)
 		return nil, fmt.Errorf("failed to get all members: %w", err)
 	}
 	defer rows.Close()
@@ -118,9 +206,10 @@ func (s *Service) GetAllMembers() ([]model.Member, error) {
 	for rows.Next() {
 		member := model.Member{}
 		err := rows.Scan(
-			&member.ID, &member.Name, &member.Verified, &member.CreatedAt, &member.UpdatedAt)
+			&member.ID, &member.Name, &member.Verified, &member.Role, &member.Suspended,
+			&member.CreatedAt, &member.UpdatedAt)
 		if err != nil {
-			log.Printf("Database error while scanning member: %v", err)
+			utils.LogErrorf("Database error while scanning member: %v", err)
 			continue
 		}
 		members = append(members, member)
@@ -129,13 +218,14 @@ func (s *Service) GetAllMembers() ([]model.Member, error) {
 	return members, nil
 }
 
-// UpdatePassword updates a member's password (only for verified members)
-func (s *Service) UpdatePassword(memberID int, currentPasswordHash, newPasswordHash string) error {
+// UpdatePassword updates a member's password (only for verified members).
+// The current password is given in plain text and checked against the stored hash.
+func (s *Service) UpdatePassword(memberID int, currentPassword, newPasswordHash string) error {
 	if memberID <= 0 {
 		return errors.New("invalid member ID")
 	}
-	if currentPasswordHash == "" {
-		return errors.New("current password hash cannot be empty")
+	if currentPassword == "" {
+		return errors.New("current password cannot be empty")
 	}
 	if newPasswordHash == "" {
 		return errors.New("new password hash cannot be empty")
@@ -153,7 +243,7 @@ func (s *Service) UpdatePassword(memberID int, currentPasswordHash, newPasswordH
 	}
 
 	// Verify current password
-	if member.PasswordHash != currentPasswordHash {
+	if !utils.VerifyPassword(currentPassword, member.PasswordHash) {
 		return errors.New("current password is incorrect")
 	}
 
@@ -165,9 +255,173 @@ func (s *Service) UpdatePassword(memberID int, currentPasswordHash, newPasswordH
 		newPasswordHash, time.Now(), memberID)
 
 	if err != nil {
-		log.Printf("Database error while updating password for member ID %d: %v", memberID, err)
+		utils.LogErrorf("Database error while updating password for member ID %d: %v", memberID, err)
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
 	return nil
 }
+
+// UpdatePasswordHash replaces a member's stored password hash without checking the
+// current password. Used to upgrade legacy hashes after a successful sign-in.
+func (s *Service) UpdatePasswordHash(memberID int, passwordHash string) error {
+	if memberID <= 0 {
+		return errors.New("invalid member ID")
+	}
+	if passwordHash == "" {
+		return errors.New("password hash cannot be empty")
+	}
+
+	_, err := s.db.Exec(`
+		UPDATE members 
+		SET password_hash = $1, updated_at = $2 
+		WHERE id = $3`,
+		passwordHash, time.Now(), memberID)
+
+	if err != nil {
+		utils.LogErrorf("Database error while updating password hash for member ID %d: %v", memberID, err)
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateProfile replaces a member's public profile (bio, avatar and links).
+// Fields are expected to be validated by the caller.
+func (s *Service) UpdateProfile(memberID int, req *model.UpdateProfileRequest) (*model.Member, error) {
+	if memberID <= 0 {
+		return nil, errors.New("invalid member ID")
+	}
+
+	links := req.Links
+	if links == nil {
+		links = []string{}
+	}
+	linksJSON, err := json.Marshal(links)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal links: %w", err)
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE members 
+		SET bio = $1, avatar_url = $2, links = $3, updated_at = $4 
+		WHERE id = $5`,
+		req.Bio, req.AvatarURL, string(linksJSON), time.Now(), memberID)
+	if err != nil {
+		utils.LogErrorf("Database error while updating profile for member ID %d: %v", memberID, err)
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, errors.New("member not found")
+	}
+
+	return s.GetMemberByID(memberID)
+}
+
+// unmarshalLinks decodes the stored JSON links of a member
+func unmarshalLinks(member *model.Member) {
+	if err := json.Unmarshal([]byte(member.LinksJSON), &member.Links); err != nil {
+		utils.LogWarnf("Failed to unmarshal links for member %d: %v", member.ID, err)
+		member.Links = []string{} // fallback to empty slice
+	}
+}
+
+// GetUnverifiedMembers returns the members waiting for approval, oldest first
+func (s *Service) GetUnverifiedMembers() ([]model.Member, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, verified, role, suspended, created_at, updated_at 
+		FROM members WHERE NOT verified AND NOT suspended ORDER BY created_at ASC`)
+	if err != nil {
+		utils.LogErrorf("Database error while getting unverified members: %v", err)
+		return nil, fmt.Errorf("failed to get unverified members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []model.Member
+	for rows.Next() {
+		member := model.Member{}
+		err := rows.Scan(
+			&member.ID, &member.Name, &member.Verified, &member.Role, &member.Suspended,
+			&member.CreatedAt, &member.UpdatedAt)
+		if err != nil {
+			utils.LogErrorf("Database error while scanning member: %v", err)
+			continue
+		}
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// VerifyMember approves a member, allowing them to create and edit sketches
+func (s *Service) VerifyMember(memberID int) error {
+	if memberID <= 0 {
+		return errors.New("invalid member ID")
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE members 
+		SET verified = true, updated_at = $1 
+		WHERE id = $2`,
+		time.Now(), memberID)
+	if err != nil {
+		utils.LogErrorf("Database error while verifying member ID %d: %v", memberID, err)
+		return fmt.Errorf("failed to verify member: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errors.New("member not found")
+	}
+
+	return nil
+}
+
+// SetSuspended suspends or reinstates a member. Suspended members cannot sign in;
+// callers should also delete their sessions.
+func (s *Service) SetSuspended(memberID int, suspended bool) error {
+	return s.updateModerationField(memberID, "suspended", suspended)
+}
+
+// SetRole changes a member's role
+func (s *Service) SetRole(memberID int, role string) error {
+	if !model.IsValidRole(role) {
+		return fmt.Errorf("invalid role '%s'", role)
+	}
+	return s.updateModerationField(memberID, "role", role)
+}
+
+// updateModerationField sets one of the member columns managed by moderators and admins
+func (s *Service) updateModerationField(memberID int, column string, value interface{}) error {
+	if memberID <= 0 {
+		return errors.New("invalid member ID")
+	}
+
+	result, err := s.db.Exec(fmt.Sprintf(`
+		UPDATE members 
+		SET %s = $1, updated_at = $2 
+		WHERE id = $3`, column),
+		value, time.Now(), memberID)
+	if err != nil {
+		utils.LogErrorf("Database error while updating %s for member ID %d: %v", column, memberID, err)
+		return fmt.Errorf("failed to update member %s: %w", column, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errors.New("member not found")
+	}
+
+	return nil
+}
@@ -3,15 +3,21 @@ package services
 import (
 	"database/sql"
 
+	"github.com/sb-luis/creative-coding-bookclub/internal/services/admin"
+	"github.com/sb-luis/creative-coding-bookclub/internal/services/invite"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services/member"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services/session"
+	"github.com/sb-luis/creative-coding-bookclub/internal/services/signin"
 	"github.com/sb-luis/creative-coding-bookclub/internal/services/sketch"
 )
 
 // Services contains all application services
 type Services struct {
+	Admin   *admin.Service
+	Invite  *invite.Service
 	Member  *member.Service
 	Session *session.Service
+	SignIn  *signin.Service
 	Sketch  *sketch.Service
 }
 
@@ -19,8 +25,11 @@ type Services struct {
 func NewServices(db *sql.DB) *Services {
 	memberService := member.NewService(db)
 	return &Services{
+		Admin:   admin.NewService(db),
+		Invite:  invite.NewService(db),
 		Member:  memberService,
 		Session: session.NewService(db),
+		SignIn:  signin.NewService(db),
 		Sketch:  sketch.NewService(db),
 	}
 }
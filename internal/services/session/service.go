@@ -1,10 +1,10 @@
 package session
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/sb-luis/creative-coding-bookclub/internal/model"
@@ -21,37 +21,60 @@ func NewService(db *sql.DB) *Service {
 	return &Service{db: db}
 }
 
+// Sessions expire after a period of inactivity, which is renewed while they are in use
+const (
+	sessionDuration           = 24 * time.Hour
+	rememberedSessionDuration = 30 * 24 * time.Hour
+	// How often last_seen_at and expires_at are refreshed, to avoid a write on every request
+	renewInterval = 5 * time.Minute
+)
+
+// sessionDurationFor returns how long a session lasts without being used
+func sessionDurationFor(remember bool) time.Duration {
+	if remember {
+		return rememberedSessionDuration
+	}
+	return sessionDuration
+}
+
 // CreateSession creates a new session for a member
-func (s *Service) CreateSession(memberID int) (*model.Session, error) {
+func (s *Service) CreateSession(memberID int, req *model.CreateSessionRequest) (*model.Session, error) {
 	if memberID <= 0 {
 		return nil, errors.New("invalid member ID")
 	}
+	if req == nil {
+		req = &model.CreateSessionRequest{}
+	}
 
 	// Generate session ID
 	sessionID, err := utils.GenerateSessionID()
 	if err != nil {
-		log.Printf("Error generating session ID for member %d: %v", memberID, err)
+		utils.LogErrorf("Error generating session ID for member %d: %v", memberID, err)
 		return nil, fmt.Errorf("failed to generate session ID: %w", err)
 	}
 
-	expiresAt := time.Now().Add(24 * time.Hour) // 24 hours
 	createdAt := time.Now()
+	expiresAt := createdAt.Add(sessionDurationFor(req.Remember))
 
 	// Insert new session
 	_, err = s.db.Exec(`
-		INSERT INTO sessions (id, member_id, created_at, expires_at) 
-		VALUES ($1, $2, $3, $4)`,
-		sessionID, memberID, createdAt, expiresAt)
+		INSERT INTO sessions (id, member_id, user_agent, ip, remember, created_at, last_seen_at, expires_at) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		sessionID, memberID, req.UserAgent, req.IP, req.Remember, createdAt, createdAt, expiresAt)
 	if err != nil {
-		log.Printf("Database error while creating session for member %d: %v", memberID, err)
+		utils.LogErrorf("Database error while creating session for member %d: %v", memberID, err)
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
 	return &model.Session{
-		ID:        sessionID,
-		MemberID:  memberID,
-		CreatedAt: createdAt,
-		ExpiresAt: expiresAt,
+		ID:         sessionID,
+		MemberID:   memberID,
+		UserAgent:  req.UserAgent,
+		IP:         req.IP,
+		Remember:   req.Remember,
+		CreatedAt:  createdAt,
+		LastSeenAt: createdAt,
+		ExpiresAt:  expiresAt,
 	}, nil
 }
 
@@ -63,15 +86,16 @@ func (s *Service) GetSession(sessionID string) (*model.Session, error) {
 
 	session := &model.Session{}
 	err := s.db.QueryRow(`
-		SELECT id, member_id, created_at, expires_at 
+		SELECT id, member_id, user_agent, ip, remember, created_at, last_seen_at, expires_at 
 		FROM sessions WHERE id = $1`, sessionID).Scan(
-		&session.ID, &session.MemberID, &session.CreatedAt, &session.ExpiresAt)
+		&session.ID, &session.MemberID, &session.UserAgent, &session.IP, &session.Remember,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("session not found")
 	}
 	if err != nil {
-		log.Printf("Database error while getting session '%s': %v", sessionID, err)
+		utils.LogErrorf("Database error while getting session: %v", err)
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
@@ -85,6 +109,54 @@ func (s *Service) GetSession(sessionID string) (*model.Session, error) {
 	return session, nil
 }
 
+// GetMemberSessions returns a member's active sessions, most recently used first
+func (s *Service) GetMemberSessions(memberID int) ([]*model.Session, error) {
+	if memberID <= 0 {
+		return nil, errors.New("invalid member ID")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, member_id, user_agent, ip, remember, created_at, last_seen_at, expires_at 
+		FROM sessions WHERE member_id = $1 AND expires_at > $2 
+		ORDER BY last_seen_at DESC`, memberID, time.Now())
+	if err != nil {
+		utils.LogErrorf("Database error while getting sessions for member %d: %v", memberID, err)
+		return nil, fmt.Errorf("failed to get member sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*model.Session
+	for rows.Next() {
+		session := &model.Session{}
+		err := rows.Scan(
+			&session.ID, &session.MemberID, &session.UserAgent, &session.IP, &session.Remember,
+			&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+		if err != nil {
+			utils.LogErrorf("Database error while scanning session for member %d: %v", memberID, err)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// DeleteMemberSession signs a member out of one of their sessions, given its public ID
+func (s *Service) DeleteMemberSession(memberID int, publicID string) error {
+	sessions, err := s.GetMemberSessions(memberID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.PublicID() == publicID {
+			return s.DeleteSession(session.ID)
+		}
+	}
+
+	return errors.New("session not found")
+}
+
 // DeleteSession deletes a session
 func (s *Service) DeleteSession(sessionID string) error {
 	if sessionID == "" {
@@ -93,21 +165,45 @@ func (s *Service) DeleteSession(sessionID string) error {
 
 	_, err := s.db.Exec("DELETE FROM sessions WHERE id = $1", sessionID)
 	if err != nil {
-		log.Printf("Database error while deleting session '%s': %v", sessionID, err)
+		utils.LogErrorf("Database error while deleting session '%s': %v", sessionID, err)
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 	return nil
 }
 
+// DeleteMemberSessions signs a member out everywhere by deleting all of their sessions
+func (s *Service) DeleteMemberSessions(memberID int) error {
+	if memberID <= 0 {
+		return errors.New("invalid member ID")
+	}
+
+	_, err := s.db.Exec("DELETE FROM sessions WHERE member_id = $1", memberID)
+	if err != nil {
+		utils.LogErrorf("Database error while deleting sessions for member %d: %v", memberID, err)
+		return fmt.Errorf("failed to delete member sessions: %w", err)
+	}
+	return nil
+}
+
 // CleanupExpiredSessions removes expired sessions from the database
-func (s *Service) CleanupExpiredSessions() error {
-	_, err := s.db.Exec("DELETE FROM sessions WHERE expires_at < $1", time.Now())
+func (s *Service) CleanupExpiredSessions(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < $1", time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to cleanup expired sessions: %w", err)
 	}
 	return nil
 }
 
+// CountActiveSessions returns the number of sessions that have not expired
+func (s *Service) CountActiveSessions(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sessions WHERE expires_at > $1", time.Now()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active sessions: %w", err)
+	}
+	return count, nil
+}
+
 // IsSessionValid checks if a session exists and is not expired
 func (s *Service) IsSessionValid(sessionID string) bool {
 	if sessionID == "" {
@@ -122,7 +218,8 @@ func (s *Service) IsSessionValid(sessionID string) bool {
 	return time.Now().Before(session.ExpiresAt)
 }
 
-// GetMemberIDFromSession retrieves the member ID associated with a session
+// GetMemberIDFromSession retrieves the member ID associated with a session.
+// Using a session renews it, so members stay signed in while they are active.
 func (s *Service) GetMemberIDFromSession(sessionID string) (int, error) {
 	if sessionID == "" {
 		return 0, errors.New("session ID cannot be empty")
@@ -134,9 +231,23 @@ func (s *Service) GetMemberIDFromSession(sessionID string) (int, error) {
 	}
 
 	// Check if session is expired
-	if time.Now().After(session.ExpiresAt) {
+	now := time.Now()
+	if now.After(session.ExpiresAt) {
 		return 0, errors.New("session expired")
 	}
 
+	// Sliding expiry: push the expiry back, at most once per renewInterval
+	if now.Sub(session.LastSeenAt) >= renewInterval {
+		_, err := s.db.Exec(`
+			UPDATE sessions 
+			SET last_seen_at = $1, expires_at = $2 
+			WHERE id = $3`,
+			now, now.Add(sessionDurationFor(session.Remember)), sessionID)
+		if err != nil {
+			// The session is still valid, it just won't be extended this time
+			utils.LogErrorf("Database error while renewing session for member %d: %v", session.MemberID, err)
+		}
+	}
+
 	return session.MemberID, nil
 }
@@ -0,0 +1,165 @@
+package signin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// Rate limiting of password guesses. Failed attempts are counted separately for the member name
+// and for the client IP. After a few free attempts each new failure doubles the wait before the
+// next attempt, until the limit is reached and sign-in is locked out for lockoutDuration.
+const (
+	baseDelay       = 30 * time.Second
+	lockoutDuration = 30 * time.Minute
+	attemptWindow   = time.Hour // Failures older than this are forgotten
+)
+
+// limitPolicy sets how many failures are allowed before backing off and before locking out
+type limitPolicy struct {
+	freeAttempts    int
+	lockoutAttempts int
+}
+
+var (
+	// Guessing one member's password
+	namePolicy = limitPolicy{freeAttempts: 5, lockoutAttempts: 10}
+	// Guessing many members' passwords from one place (looser, as members may share an IP)
+	ipPolicy = limitPolicy{freeAttempts: 20, lockoutAttempts: 100}
+)
+
+// Service records failed sign-in attempts and decides when to refuse new ones
+type Service struct {
+	db *sql.DB
+}
+
+// NewService creates a new sign-in attempt service
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// normalizeName makes the limit apply to a name whatever its case
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// delay returns how long to wait after the last of the given number of failures
+func (p limitPolicy) delay(failures int) time.Duration {
+	if failures < p.freeAttempts {
+		return 0
+	}
+	if failures >= p.lockoutAttempts {
+		return lockoutDuration
+	}
+
+	// Doubling stops at lockoutDuration, so that many failures cannot overflow the duration
+	delay := baseDelay
+	for i := p.freeAttempts; i < failures && delay < lockoutDuration; i++ {
+		delay *= 2
+	}
+	return min(delay, lockoutDuration)
+}
+
+// attemptLockKey derives the advisory lock key that serializes sign-in attempts for a name or IP
+func attemptLockKey(kind, value string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("sign-in:" + kind + ":" + value))
+	return int64(hash.Sum64())
+}
+
+// BeginAttempt decides whether the client may try to sign in as the member now. If it may, the
+// attempt is recorded as a failure straight away and 0 is returned; a successful sign-in then
+// forgets it with ClearFailures. Otherwise it returns how long the client must wait.
+//
+// Checking and recording happen under advisory locks on the name and the IP, so that a burst of
+// parallel guesses is counted one by one instead of all passing the check before any is recorded.
+func (s *Service) BeginAttempt(name, ip string) (time.Duration, error) {
+	if ip == "" {
+		return 0, errors.New("ip cannot be empty")
+	}
+	name = normalizeName(name)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Always lock the name before the IP, so that concurrent attempts cannot deadlock
+	for _, key := range []int64{attemptLockKey("name", name), attemptLockKey("ip", ip)} {
+		if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", key); err != nil {
+			utils.LogErrorf("Database error while locking sign-in attempts for '%s' from %s: %v", name, ip, err)
+			return 0, fmt.Errorf("failed to lock sign-in attempts: %w", err)
+		}
+	}
+
+	now := time.Now()
+	var nameFailures, ipFailures int
+	var lastNameFailure, lastIPFailure sql.NullTime
+	err = tx.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE member_name = $1), MAX(attempted_at) FILTER (WHERE member_name = $1),
+			COUNT(*) FILTER (WHERE ip = $2), MAX(attempted_at) FILTER (WHERE ip = $2)
+		FROM sign_in_attempts
+		WHERE attempted_at > $3 AND (member_name = $1 OR ip = $2)`,
+		name, ip, now.Add(-attemptWindow)).Scan(&nameFailures, &lastNameFailure, &ipFailures, &lastIPFailure)
+	if err != nil {
+		utils.LogErrorf("Database error while counting sign-in attempts for '%s' from %s: %v", name, ip, err)
+		return 0, fmt.Errorf("failed to count sign-in attempts: %w", err)
+	}
+
+	var wait time.Duration
+	if lastNameFailure.Valid {
+		wait = lastNameFailure.Time.Add(namePolicy.delay(nameFailures)).Sub(now)
+	}
+	if lastIPFailure.Valid {
+		if ipWait := lastIPFailure.Time.Add(ipPolicy.delay(ipFailures)).Sub(now); ipWait > wait {
+			wait = ipWait
+		}
+	}
+	if wait > 0 {
+		// Refused attempts are not recorded, so waiting out the delay is enough to try again
+		return wait, nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO sign_in_attempts (member_name, ip, attempted_at)
+		VALUES ($1, $2, $3)`,
+		name, ip, now)
+	if err != nil {
+		utils.LogErrorf("Database error while recording sign-in attempt for '%s' from %s: %v", name, ip, err)
+		return 0, fmt.Errorf("failed to record sign-in attempt: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return 0, nil
+}
+
+// ClearFailures forgets the failed attempts for a member name after a successful sign-in,
+// including the one recorded by BeginAttempt.
+// Failures from the client IP against other names are kept.
+func (s *Service) ClearFailures(name string) error {
+	_, err := s.db.Exec("DELETE FROM sign_in_attempts WHERE member_name = $1", normalizeName(name))
+	if err != nil {
+		utils.LogErrorf("Database error while clearing sign-in attempts for '%s': %v", name, err)
+		return fmt.Errorf("failed to clear sign-in attempts: %w", err)
+	}
+	return nil
+}
+
+// CleanupOldAttempts removes attempts that no longer count towards any limit
+func (s *Service) CleanupOldAttempts(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sign_in_attempts WHERE attempted_at < $1", time.Now().Add(-attemptWindow))
+	if err != nil {
+		return fmt.Errorf("failed to cleanup old sign-in attempts: %w", err)
+	}
+	return nil
+}
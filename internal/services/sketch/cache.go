@@ -0,0 +1,130 @@
+package sketch
+
+import (
+	"container/list"
+	"slices"
+	"sync"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+)
+
+// sketchCacheSize is how many sketches are kept in memory. Most sketches are a few KB of source.
+const sketchCacheSize = 256
+
+// sketchCacheKey identifies a sketch the way pages and the code endpoint look it up
+type sketchCacheKey struct {
+	memberID int
+	slug     string
+}
+
+// sketchCache is a least-recently-used cache of sketches by member and slug, so that showing a
+// sketch (its page, its iframe and its code) does not read the whole sketch every time.
+// The service removes a sketch from the cache whenever it changes or deletes it, and checks
+// its version, visibility and hidden flag before serving it, as other server processes sharing
+// the database may have changed it.
+type sketchCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Of *model.Sketch, most recently used first
+	entries  map[sketchCacheKey]*list.Element
+	keysByID map[int]sketchCacheKey
+
+	// generation changes on every removal, so that a sketch read from the database before
+	// a concurrent update is not added back to the cache after the update removed it
+	generation uint64
+}
+
+// newSketchCache creates an empty cache holding at most capacity sketches
+func newSketchCache(capacity int) *sketchCache {
+	return &sketchCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[sketchCacheKey]*list.Element),
+		keysByID: make(map[int]sketchCacheKey),
+	}
+}
+
+// copySketch returns a copy of a sketch that shares nothing with the original,
+// so that callers can modify what they get from the cache
+func copySketch(sketch *model.Sketch) *model.Sketch {
+	c := *sketch
+	c.Tags = slices.Clone(sketch.Tags)
+	c.ExternalLibs = slices.Clone(sketch.ExternalLibs)
+	if sketch.ForkedFromSketchID != nil {
+		forkedFromID := *sketch.ForkedFromSketchID
+		c.ForkedFromSketchID = &forkedFromID
+	}
+	return &c
+}
+
+// get returns a copy of the cached sketch, and the current generation to pass to add
+// when the sketch is not cached and has to be read from the database
+func (c *sketchCache) get(memberID int, slug string) (*model.Sketch, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[sketchCacheKey{memberID, slug}]
+	if !ok {
+		return nil, c.generation, false
+	}
+	c.order.MoveToFront(element)
+	return copySketch(element.Value.(*model.Sketch)), c.generation, true
+}
+
+// add caches a sketch read from the database, unless a sketch was removed since the
+// generation was returned by get, as the sketch may then be out of date already
+func (c *sketchCache) add(sketch *model.Sketch, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	key := sketchCacheKey{sketch.MemberID, sketch.Slug}
+	if element, ok := c.entries[key]; ok {
+		element.Value = copySketch(sketch)
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(copySketch(sketch))
+	c.keysByID[sketch.ID] = key
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Remove(c.order.Back()).(*model.Sketch)
+		delete(c.entries, sketchCacheKey{oldest.MemberID, oldest.Slug})
+		delete(c.keysByID, oldest.ID)
+	}
+}
+
+// remove drops a sketch from the cache by ID
+func (c *sketchCache) remove(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if key, ok := c.keysByID[id]; ok {
+		c.removeKey(key)
+	}
+}
+
+// removeBySlug drops a sketch from the cache by member and slug
+func (c *sketchCache) removeBySlug(memberID int, slug string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.removeKey(sketchCacheKey{memberID, slug})
+}
+
+// removeKey drops a cached sketch. The caller must hold the lock.
+func (c *sketchCache) removeKey(key sketchCacheKey) {
+	element, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	sketch := c.order.Remove(element).(*model.Sketch)
+	delete(c.entries, key)
+	delete(c.keysByID, sketch.ID)
+}
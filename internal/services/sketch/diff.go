@@ -0,0 +1,181 @@
+package sketch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change
+const diffContextLines = 3
+
+// diffOp is a single line of an edit script: ' ' (equal), '-' (delete) or '+' (insert)
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// splitLines splits text into lines, keeping the trailing newline on each line
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the shortest edit script between two sets of lines
+// using Myers' O(ND) algorithm
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] holds the furthest reaching x for diagonals -d..d before step d
+	var trace [][]int
+
+search:
+	for d := 0; d <= maxD; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the edit script
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		snapshot := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && snapshot[k-1+d] < snapshot[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := snapshot[prevK+d]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{kind: ' ', line: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, diffOp{kind: '+', line: b[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{kind: '-', line: a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, diffOp{kind: ' ', line: a[x-1]})
+		x--
+		y--
+	}
+
+	// Reverse into forward order
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// unifiedDiff returns a unified diff between two texts, or an empty string if they are equal
+func unifiedDiff(fromName, toName, from, to string) string {
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	// Line positions in the old and new text before each op (0-based)
+	fromPos := make([]int, len(ops)+1)
+	toPos := make([]int, len(ops)+1)
+	hasChanges := false
+	for i, op := range ops {
+		fromPos[i+1], toPos[i+1] = fromPos[i], toPos[i]
+		if op.kind != '+' {
+			fromPos[i+1]++
+		}
+		if op.kind != '-' {
+			toPos[i+1]++
+		}
+		if op.kind != ' ' {
+			hasChanges = true
+		}
+	}
+	if !hasChanges {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	i := 0
+	for i < len(ops) {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Grow the hunk until the gap to the next change is too wide to merge
+		start := max(0, i-diffContextLines)
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			run := 0
+			for end+run < len(ops) && ops[end+run].kind == ' ' {
+				run++
+			}
+			if end+run < len(ops) && run <= 2*diffContextLines {
+				end += run
+				continue
+			}
+			end += min(run, diffContextLines)
+			break
+		}
+
+		fromStart, fromLen := fromPos[start], fromPos[end]-fromPos[start]
+		toStart, toLen := toPos[start], toPos[end]-toPos[start]
+		if fromLen > 0 {
+			fromStart++
+		}
+		if toLen > 0 {
+			toStart++
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", fromStart, fromLen, toStart, toLen)
+
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,174 @@
+package sketch
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 100
+)
+
+// listedCondition matches the sketches that are listed publicly: public ones not hidden by a moderator
+const listedCondition = "(s.visibility = 'public' AND NOT s.hidden)"
+
+// sketchSortOrder describes how a listing sort maps onto SQL
+type sketchSortOrder struct {
+	column string // Sketch column to order by (ties are broken by id)
+	cast   string // Type of the cursor value parameter
+	desc   bool
+}
+
+var sketchSortOrders = map[string]sketchSortOrder{
+	model.SortUpdated: {column: "s.updated_at", cast: "timestamp", desc: true},
+	model.SortCreated: {column: "s.created_at", cast: "timestamp", desc: true},
+	model.SortTitle:   {column: "s.title", cast: "text", desc: false},
+}
+
+// listCursor is the position of the last sketch of a page, encoded as an opaque string for clients
+type listCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// tagFilter returns a condition matching sketches with the tag passed as the given parameter.
+// Tags are stored as JSON text, so rows whose tags are empty or not valid JSON are skipped
+// rather than failing the cast for the whole query (pg_input_is_valid needs Postgres 16).
+func tagFilter(param string) string {
+	return fmt.Sprintf("CASE WHEN pg_input_is_valid(s.tags, 'jsonb') THEN s.tags::jsonb @> jsonb_build_array(%s::text) ELSE false END", param)
+}
+
+// externalLibFilter returns a condition matching sketches with an external library URL
+// containing the text passed as the given parameter
+func externalLibFilter(param string) string {
+	return fmt.Sprintf("strpos(lower(s.external_libs), lower(%s)) > 0", param)
+}
+
+// listQuery builds the WHERE, ORDER BY and LIMIT clauses of a paginated sketch listing.
+// Pagination is keyset based: the cursor holds the sort value and id of the last sketch seen.
+type listQuery struct {
+	sort       string
+	order      sketchSortOrder
+	limit      int
+	conditions []string
+	args       []interface{}
+}
+
+// newListQuery validates listing options and applies their filters and cursor
+func newListQuery(opts *model.ListSketchesOptions) (*listQuery, error) {
+	if opts == nil {
+		opts = &model.ListSketchesOptions{}
+	}
+
+	q := &listQuery{sort: opts.Sort, limit: opts.Limit}
+	if q.sort == "" {
+		q.sort = model.SortUpdated
+	}
+	order, ok := sketchSortOrders[q.sort]
+	if !ok {
+		return nil, errors.New("invalid sort")
+	}
+	q.order = order
+
+	if q.limit <= 0 {
+		q.limit = defaultListLimit
+	}
+	if q.limit > maxListLimit {
+		q.limit = maxListLimit
+	}
+
+	if opts.Tag != "" {
+		q.where(tagFilter(q.arg(opts.Tag)))
+	}
+	if opts.ExternalLib != "" {
+		q.where(externalLibFilter(q.arg(opts.ExternalLib)))
+	}
+
+	if opts.Cursor != "" {
+		cursor, err := decodeListCursor(opts.Cursor)
+		if err != nil || cursor.Sort != q.sort {
+			return nil, errors.New("invalid cursor")
+		}
+
+		var value interface{} = cursor.Value
+		if order.cast == "timestamp" {
+			t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+			if err != nil {
+				return nil, errors.New("invalid cursor")
+			}
+			value = t
+		}
+
+		comparison := ">"
+		if order.desc {
+			comparison = "<"
+		}
+		q.where(fmt.Sprintf("(%s, s.id) %s (%s::%s, %s)", order.column, comparison, q.arg(value), order.cast, q.arg(cursor.ID)))
+	}
+
+	return q, nil
+}
+
+// arg adds a query argument and returns its placeholder
+func (q *listQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// where adds a condition that all listed sketches must match
+func (q *listQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// clauses returns the WHERE, ORDER BY and LIMIT clauses. One extra row is fetched to tell whether there is a next page.
+func (q *listQuery) clauses() string {
+	var clauses strings.Builder
+	if len(q.conditions) > 0 {
+		clauses.WriteString("WHERE " + strings.Join(q.conditions, " AND ") + "\n")
+	}
+
+	direction := "ASC"
+	if q.order.desc {
+		direction = "DESC"
+	}
+	fmt.Fprintf(&clauses, "ORDER BY %s %s, s.id %s\n", q.order.column, direction, direction)
+	fmt.Fprintf(&clauses, "LIMIT %s", q.arg(q.limit+1))
+	return clauses.String()
+}
+
+// nextCursor returns the cursor for the page after the given sketch (the last one of the current page)
+func (q *listQuery) nextCursor(sketch *model.Sketch) string {
+	cursor := listCursor{Sort: q.sort, ID: sketch.ID}
+	switch q.sort {
+	case model.SortCreated:
+		cursor.Value = sketch.CreatedAt.Format(time.RFC3339Nano)
+	case model.SortTitle:
+		cursor.Value = sketch.Title
+	default:
+		cursor.Value = sketch.UpdatedAt.Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListCursor parses a cursor previously returned by nextCursor
+func decodeListCursor(encoded string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,91 @@
+package sketch
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+// GetSketchesForModeration returns a page of sketches from all members whatever their visibility,
+// and the cursor of the next page. Only moderators should be shown this listing.
+func (s *Service) GetSketchesForModeration(opts *model.ListSketchesOptions) ([]model.ModerationSketch, string, error) {
+	q, err := newListQuery(opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Build the clauses first, as they add the LIMIT argument
+	clauses := q.clauses()
+	rows, err := s.db.Query(`
+		SELECT s.id, s.slug, s.title, s.visibility, s.hidden, s.created_at, s.updated_at, m.name
+		FROM sketches s
+		JOIN members m ON s.member_id = m.id
+		`+clauses, q.args...)
+	if err != nil {
+		utils.LogErrorf("Database error while getting sketches for moderation: %v", err)
+		return nil, "", fmt.Errorf("failed to get sketches for moderation: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.ModerationSketch
+	var last model.Sketch
+	nextCursor := ""
+	for rows.Next() {
+		var sketch model.Sketch
+		var memberName string
+		err := rows.Scan(
+			&sketch.ID, &sketch.Slug, &sketch.Title, &sketch.Visibility, &sketch.Hidden,
+			&sketch.CreatedAt, &sketch.UpdatedAt, &memberName)
+		if err != nil {
+			utils.LogErrorf("Database error while scanning sketch for moderation: %v", err)
+			continue
+		}
+
+		// The extra row only tells that there is a next page
+		if len(result) == q.limit {
+			nextCursor = q.nextCursor(&last)
+			break
+		}
+		last = sketch
+
+		result = append(result, model.ModerationSketch{
+			ID:         sketch.ID,
+			MemberName: memberName,
+			Slug:       sketch.Slug,
+			Title:      sketch.Title,
+			Visibility: sketch.Visibility,
+			Hidden:     sketch.Hidden,
+			UpdatedAt:  sketch.UpdatedAt,
+		})
+	}
+
+	return result, nextCursor, nil
+}
+
+// SetSketchHidden hides a sketch from everyone but its owner, or makes it visible again.
+// Unlike visibility, this is set by moderators and cannot be changed by the owner.
+func (s *Service) SetSketchHidden(id int, hidden bool) error {
+	if id <= 0 {
+		return errors.New("invalid sketch ID")
+	}
+
+	// updated_at is left alone so that hiding a sketch does not bump it in listings
+	result, err := s.db.Exec("UPDATE sketches SET hidden = $1 WHERE id = $2", hidden, id)
+	if err != nil {
+		utils.LogErrorf("Database error while setting hidden=%t for sketch %d: %v", hidden, id, err)
+		return fmt.Errorf("failed to update sketch: %w", err)
+	}
+	s.cache.remove(id)
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errors.New("sketch not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package sketch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+
+	// Control characters used by ts_headline to mark matches, so that the
+	// snippet can be HTML-escaped before the markers become <mark> tags
+	snippetStartSel = "\x02"
+	snippetStopSel  = "\x03"
+)
+
+// snippetReplacer turns ts_headline match markers into HTML highlights
+var snippetReplacer = strings.NewReplacer(snippetStartSel, "<mark>", snippetStopSel, "</mark>")
+
+// SearchSketches runs a ranked full-text search over public sketches.
+// Results are ordered by relevance and include a highlighted snippet.
+func (s *Service) SearchSketches(req *model.SearchSketchesRequest) ([]model.SketchInfo, error) {
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, errors.New("search query is required")
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	// $1 is always the query text
+	args := []interface{}{query}
+	match := "s.search_vector @@ websearch_to_tsquery('english', $1)"
+	rank := "ts_rank(s.search_vector, websearch_to_tsquery('english', $1))"
+	document := "concat_ws(' ', s.title, s.description, s.keywords)"
+	if req.IncludeSourceCode {
+		// Source code matches count, but rank well below metadata matches
+		match = "(" + match + " OR s.source_vector @@ websearch_to_tsquery('simple', $1))"
+		rank += " + 0.1 * ts_rank(s.source_vector, websearch_to_tsquery('simple', $1))"
+		document = "concat_ws(' ', s.title, s.description, s.keywords, left(s.source_code, 20000))"
+	}
+
+	conditions := []string{listedCondition, match}
+	if req.MemberName != "" {
+		args = append(args, req.MemberName)
+		conditions = append(conditions, fmt.Sprintf("m.name = $%d", len(args)))
+	}
+	if req.Tag != "" {
+		args = append(args, req.Tag)
+		conditions = append(conditions, tagFilter(fmt.Sprintf("$%d", len(args))))
+	}
+	if req.ExternalLib != "" {
+		args = append(args, req.ExternalLib)
+		conditions = append(conditions, externalLibFilter(fmt.Sprintf("$%d", len(args))))
+	}
+	if req.From != nil {
+		args = append(args, *req.From)
+		conditions = append(conditions, fmt.Sprintf("s.created_at >= $%d", len(args)))
+	}
+	if req.To != nil {
+		args = append(args, *req.To)
+		conditions = append(conditions, fmt.Sprintf("s.created_at < $%d", len(args)))
+	}
+	args = append(args, limit)
+
+	// Headlines are expensive, so they are only built for the page of results being returned
+	sqlQuery := fmt.Sprintf(`
+		SELECT r.slug, r.title, r.description, r.keywords, r.tags, r.member_name, r.rank,
+			ts_headline('english', r.document, websearch_to_tsquery('english', $1),
+				'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MinWords=10, MaxWords=30, MaxFragments=2, FragmentDelimiter=" … "')
+		FROM (
+			SELECT s.slug, s.title, s.description, s.keywords, s.tags, s.updated_at, m.name AS member_name,
+				%s AS rank, %s AS document
+			FROM sketches s
+			JOIN members m ON s.member_id = m.id
+			WHERE %s
+			ORDER BY rank DESC, s.updated_at DESC
+			LIMIT $%d
+		) r
+		ORDER BY r.rank DESC, r.updated_at DESC`,
+		rank, document, strings.Join(conditions, " AND "), len(args))
+
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		utils.LogErrorf("Database error while searching sketches: %v", err)
+		return nil, fmt.Errorf("failed to search sketches: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.SketchInfo
+	for rows.Next() {
+		var slug, title, description, keywords, tagsJSON, memberName, headline string
+		var rank float64
+		if err := rows.Scan(&slug, &title, &description, &keywords, &tagsJSON, &memberName, &rank, &headline); err != nil {
+			utils.LogErrorf("Database error while scanning search result: %v", err)
+			continue
+		}
+
+		sketchInfo := model.SketchInfo{
+			Slug:    slug,
+			URL:     fmt.Sprintf("/sketches/%s/%s", memberName, slug),
+			Alias:   memberName,
+			Rank:    rank,
+			Snippet: snippetReplacer.Replace(html.EscapeString(headline)),
+		}
+
+		// Set pointers for optional fields
+		if title != "" {
+			sketchInfo.Title = &title
+		}
+		if description != "" {
+			sketchInfo.Description = &description
+		}
+		if keywords != "" {
+			sketchInfo.Keywords = &keywords
+		}
+		var tags []string
+		if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
+			utils.LogWarnf("Failed to unmarshal tags for sketch %s/%s: %v", memberName, slug, err)
+		} else if len(tags) > 0 {
+			sketchInfo.Tags = tags
+		}
+
+		result = append(result, sketchInfo)
+	}
+
+	if err := rows.Err(); err != nil {
+		utils.LogErrorf("Database error while iterating search results: %v", err)
+		return nil, fmt.Errorf("failed to search sketches: %w", err)
+	}
+
+	return result, nil
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/sb-luis/creative-coding-bookclub/internal/model"
+	"github.com/sb-luis/creative-coding-bookclub/internal/utils"
 )
 
 // generateSlug creates a URL-friendly slug from a title
@@ -36,12 +37,13 @@ func generateSlug(title string) string {
 
 // Service handles sketch-related business logic
 type Service struct {
-	db *sql.DB
+	db    *sql.DB
+	cache *sketchCache
 }
 
 // NewService creates a new sketch service
 func NewService(db *sql.DB) *Service {
-	return &Service{db: db}
+	return &Service{db: db, cache: newSketchCache(sketchCacheSize)}
 }
 
 // CreateSketch creates a new sketch for a member
@@ -69,7 +71,7 @@ func (s *Service) CreateSketch(memberID int, req *model.CreateSketchRequest) (*m
 	var count int
 	err := s.db.QueryRow("SELECT COUNT(*) FROM sketches WHERE member_id = $1 AND slug = $2", memberID, slug).Scan(&count)
 	if err != nil {
-		log.Printf("Database error while checking if sketch slug exists for member %d, slug '%s': %v", memberID, slug, err)
+		utils.LogErrorf("Database error while checking if sketch slug exists for member %d, slug '%s': %v", memberID, slug, err)
 		return nil, fmt.Errorf("failed to check if sketch slug exists: %w", err)
 	}
 	if count > 0 {
@@ -86,6 +88,15 @@ func (s *Service) CreateSketch(memberID int, req *model.CreateSketchRequest) (*m
 		return nil, fmt.Errorf("failed to marshal external libs: %w", err)
 	}
 
+	// Sketches are public unless requested otherwise
+	visibility := model.VisibilityPublic
+	if req.Visibility != "" {
+		if !model.IsValidVisibility(req.Visibility) {
+			return nil, fmt.Errorf("invalid visibility '%s'", req.Visibility)
+		}
+		visibility = req.Visibility
+	}
+
 	now := time.Now()
 	createdAt := now
 	updatedAt := now
@@ -98,16 +109,31 @@ func (s *Service) CreateSketch(memberID int, req *model.CreateSketchRequest) (*m
 		updatedAt = *req.UpdatedAt
 	}
 
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	var id int
-	err = s.db.QueryRow(`
-		INSERT INTO sketches (member_id, slug, title, description, keywords, tags, external_libs, source_code, created_at, updated_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
-		memberID, slug, req.Title, req.Description, req.Keywords, string(tagsJSON), string(externalLibsJSON), req.SourceCode, createdAt, updatedAt).Scan(&id)
+	err = tx.QueryRow(`
+		INSERT INTO sketches (member_id, slug, title, description, keywords, tags, external_libs, source_code, forked_from_sketch_id, visibility, created_at, updated_at) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+		memberID, slug, req.Title, req.Description, req.Keywords, string(tagsJSON), string(externalLibsJSON), req.SourceCode, req.ForkedFromSketchID, visibility, createdAt, updatedAt).Scan(&id)
 	if err != nil {
-		log.Printf("Database error while creating sketch for member %d: %v", memberID, err)
+		utils.LogErrorf("Database error while creating sketch for member %d: %v", memberID, err)
 		return nil, fmt.Errorf("failed to create sketch: %w", err)
 	}
 
+	// Record the initial source code as the first revision
+	if err := s.recordRevision(tx, id, req.SourceCode, updatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit sketch creation: %w", err)
+	}
+
 	return s.GetSketchByID(id)
 }
 
@@ -119,33 +145,55 @@ func (s *Service) GetSketchByID(id int) (*model.Sketch, error) {
 
 	sketch := &model.Sketch{}
 	err := s.db.QueryRow(`
-		SELECT id, member_id, slug, title, description, keywords, tags, external_libs, source_code, created_at, updated_at 
+		SELECT id, member_id, slug, title, description, keywords, tags, external_libs, source_code, forked_from_sketch_id, visibility, hidden, version, created_at, updated_at 
 		FROM sketches WHERE id = $1`, id).Scan(
 		&sketch.ID, &sketch.MemberID, &sketch.Slug, &sketch.Title, &sketch.Description,
 		&sketch.Keywords, &sketch.TagsJSON, &sketch.ExternalLibsJSON, &sketch.SourceCode,
-		&sketch.CreatedAt, &sketch.UpdatedAt)
+		&sketch.ForkedFromSketchID, &sketch.Visibility, &sketch.Hidden, &sketch.Version, &sketch.CreatedAt, &sketch.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("sketch not found")
 	}
 	if err != nil {
-		log.Printf("Database error while getting sketch by ID %d: %v", id, err)
+		utils.LogErrorf("Database error while getting sketch by ID %d: %v", id, err)
 		return nil, fmt.Errorf("failed to get sketch by ID: %w", err)
 	}
 
 	// Unmarshal JSON fields
 	if err := json.Unmarshal([]byte(sketch.TagsJSON), &sketch.Tags); err != nil {
-		log.Printf("Warning: failed to unmarshal tags for sketch %d: %v", id, err)
+		utils.LogWarnf("Failed to unmarshal tags for sketch %d: %v", id, err)
 		sketch.Tags = []string{} // fallback to empty slice
 	}
 	if err := json.Unmarshal([]byte(sketch.ExternalLibsJSON), &sketch.ExternalLibs); err != nil {
-		log.Printf("Warning: failed to unmarshal external libs for sketch %d: %v", id, err)
+		utils.LogWarnf("Failed to unmarshal external libs for sketch %d: %v", id, err)
 		sketch.ExternalLibs = []string{} // fallback to empty slice
 	}
 
 	return sketch, nil
 }
 
+// cachedSketchIsCurrent checks that a cached sketch has not been changed, hidden or deleted
+// since it was cached, possibly by another server process. It reads a few columns rather than
+// the whole sketch, so that a cache hit still saves reading the source code.
+func (s *Service) cachedSketchIsCurrent(cached *model.Sketch) bool {
+	var version int
+	var visibility string
+	var hidden bool
+	err := s.db.QueryRow(`
+		SELECT version, visibility, hidden
+		FROM sketches WHERE id = $1 AND member_id = $2 AND slug = $3`,
+		cached.ID, cached.MemberID, cached.Slug).Scan(&version, &visibility, &hidden)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			utils.LogErrorf("Database error while checking cached sketch %d: %v", cached.ID, err)
+		}
+		return false
+	}
+
+	// Hiding a sketch does not change its version
+	return version == cached.Version && visibility == cached.Visibility && hidden == cached.Hidden
+}
+
 // GetSketchByMemberAndSlug returns a sketch by member ID and slug
 func (s *Service) GetSketchByMemberAndSlug(memberID int, slug string) (*model.Sketch, error) {
 	if memberID <= 0 {
@@ -170,90 +218,120 @@ func (s *Service) GetSketchByMemberAndSlug(memberID int, slug string) (*model.Sk
 			Tags:         []string{"creative-coding", "p5js"},
 			ExternalLibs: []string{"https://cdn.jsdelivr.net/npm/p5@1.11.7/lib/p5.min.js"},
 			SourceCode:   defaultCode,
+			Visibility:   model.VisibilityPublic,
 			CreatedAt:    time.Now(),
 			UpdatedAt:    time.Now(),
 		}, nil
 	}
 
+	cached, generation, ok := s.cache.get(memberID, slug)
+	if ok {
+		if s.cachedSketchIsCurrent(cached) {
+			return cached, nil
+		}
+		s.cache.remove(cached.ID)
+		_, generation, _ = s.cache.get(memberID, slug)
+	}
+
 	sketch := &model.Sketch{}
 	err := s.db.QueryRow(`
-		SELECT id, member_id, slug, title, description, keywords, tags, external_libs, source_code, created_at, updated_at 
+		SELECT id, member_id, slug, title, description, keywords, tags, external_libs, source_code, forked_from_sketch_id, visibility, hidden, version, created_at, updated_at 
 		FROM sketches WHERE member_id = $1 AND slug = $2`, memberID, slug).Scan(
 		&sketch.ID, &sketch.MemberID, &sketch.Slug, &sketch.Title, &sketch.Description,
 		&sketch.Keywords, &sketch.TagsJSON, &sketch.ExternalLibsJSON, &sketch.SourceCode,
-		&sketch.CreatedAt, &sketch.UpdatedAt)
+		&sketch.ForkedFromSketchID, &sketch.Visibility, &sketch.Hidden, &sketch.Version, &sketch.CreatedAt, &sketch.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("sketch not found")
 	}
 	if err != nil {
-		log.Printf("Database error while getting sketch by member %d and slug '%s': %v", memberID, slug, err)
+		utils.LogErrorf("Database error while getting sketch by member %d and slug '%s': %v", memberID, slug, err)
 		return nil, fmt.Errorf("failed to get sketch by member and slug: %w", err)
 	}
 
 	// Unmarshal JSON fields
 	if err := json.Unmarshal([]byte(sketch.TagsJSON), &sketch.Tags); err != nil {
-		log.Printf("Warning: failed to unmarshal tags for sketch %d: %v", sketch.ID, err)
+		utils.LogWarnf("Failed to unmarshal tags for sketch %d: %v", sketch.ID, err)
 		sketch.Tags = []string{} // fallback to empty slice
 	}
 	if err := json.Unmarshal([]byte(sketch.ExternalLibsJSON), &sketch.ExternalLibs); err != nil {
-		log.Printf("Warning: failed to unmarshal external libs for sketch %d: %v", sketch.ID, err)
+		utils.LogWarnf("Failed to unmarshal external libs for sketch %d: %v", sketch.ID, err)
 		sketch.ExternalLibs = []string{} // fallback to empty slice
 	}
 
+	s.cache.add(sketch, generation)
 	return sketch, nil
 }
 
-// GetSketchesByMember returns all sketches for a member
-func (s *Service) GetSketchesByMember(memberID int) ([]*model.Sketch, error) {
+// GetSketchesByMember returns a page of sketches for a member (without source code) and the cursor of the next page.
+// Unlisted, private and moderator-hidden sketches are only included when includeHidden is true (e.g. for their owner).
+// The cursor is empty when there are no more pages.
+func (s *Service) GetSketchesByMember(memberID int, includeHidden bool, opts *model.ListSketchesOptions) ([]*model.Sketch, string, error) {
 	if memberID <= 0 {
-		return nil, errors.New("invalid member ID")
+		return nil, "", errors.New("invalid member ID")
+	}
+
+	q, err := newListQuery(opts)
+	if err != nil {
+		return nil, "", err
 	}
+	q.where("s.member_id = " + q.arg(memberID))
+	q.where("(" + q.arg(includeHidden) + " OR " + listedCondition + ")")
 
+	// Build the clauses first, as they add the LIMIT argument
+	clauses := q.clauses()
 	rows, err := s.db.Query(`
-		SELECT id, member_id, slug, title, description, keywords, tags, external_libs, source_code, created_at, updated_at 
-		FROM sketches WHERE member_id = $1 ORDER BY updated_at DESC`, memberID)
+		SELECT s.id, s.member_id, s.slug, s.title, s.description, s.keywords, s.tags, s.external_libs, s.forked_from_sketch_id, s.visibility, s.hidden, s.version, s.created_at, s.updated_at 
+		FROM sketches s
+		`+clauses, q.args...)
 	if err != nil {
-		log.Printf("Database error while getting sketches for member %d: %v", memberID, err)
-		return nil, fmt.Errorf("failed to get sketches by member: %w", err)
+		utils.LogErrorf("Database error while getting sketches for member %d: %v", memberID, err)
+		return nil, "", fmt.Errorf("failed to get sketches by member: %w", err)
 	}
 	defer rows.Close()
 
 	var sketches []*model.Sketch
+	nextCursor := ""
 	for rows.Next() {
 		sketch := &model.Sketch{}
 		err := rows.Scan(
 			&sketch.ID, &sketch.MemberID, &sketch.Slug, &sketch.Title, &sketch.Description,
-			&sketch.Keywords, &sketch.TagsJSON, &sketch.ExternalLibsJSON, &sketch.SourceCode,
-			&sketch.CreatedAt, &sketch.UpdatedAt)
+			&sketch.Keywords, &sketch.TagsJSON, &sketch.ExternalLibsJSON,
+			&sketch.ForkedFromSketchID, &sketch.Visibility, &sketch.Hidden, &sketch.Version, &sketch.CreatedAt, &sketch.UpdatedAt)
 		if err != nil {
-			log.Printf("Database error while scanning sketch for member %d: %v", memberID, err)
+			utils.LogErrorf("Database error while scanning sketch for member %d: %v", memberID, err)
 			continue
 		}
 
+		// The extra row only tells that there is a next page
+		if len(sketches) == q.limit {
+			nextCursor = q.nextCursor(sketches[len(sketches)-1])
+			break
+		}
+
 		// Unmarshal JSON fields
 		if err := json.Unmarshal([]byte(sketch.TagsJSON), &sketch.Tags); err != nil {
-			log.Printf("Warning: failed to unmarshal tags for sketch %d: %v", sketch.ID, err)
+			utils.LogWarnf("Failed to unmarshal tags for sketch %d: %v", sketch.ID, err)
 			sketch.Tags = []string{} // fallback to empty slice
 		}
 		if err := json.Unmarshal([]byte(sketch.ExternalLibsJSON), &sketch.ExternalLibs); err != nil {
-			log.Printf("Warning: failed to unmarshal external libs for sketch %d: %v", sketch.ID, err)
+			utils.LogWarnf("Failed to unmarshal external libs for sketch %d: %v", sketch.ID, err)
 			sketch.ExternalLibs = []string{} // fallback to empty slice
 		}
 
 		sketches = append(sketches, sketch)
 	}
 
-	return sketches, nil
+	return sketches, nextCursor, nil
 }
 
-// GetAllSketches returns all sketches from all members
+// GetAllSketches returns all public sketches from all members
 func (s *Service) GetAllSketches() ([]*model.Sketch, error) {
 	rows, err := s.db.Query(`
-		SELECT id, member_id, slug, title, description, keywords, tags, external_libs, source_code, created_at, updated_at 
-		FROM sketches ORDER BY updated_at DESC`)
+		SELECT id, member_id, slug, title, description, keywords, tags, external_libs, source_code, forked_from_sketch_id, visibility, hidden, created_at, updated_at 
+		FROM sketches s WHERE ` + listedCondition + ` ORDER BY updated_at DESC`)
 	if err != nil {
-		log.Printf("Database error while getting all sketches: %v", err)
+		utils.LogErrorf("Database error while getting all sketches: %v", err)
 		return nil, fmt.Errorf("failed to get all sketches: %w", err)
 	}
 	defer rows.Close()
@@ -264,19 +342,19 @@ func (s *Service) GetAllSketches() ([]*model.Sketch, error) {
 		err := rows.Scan(
 			&sketch.ID, &sketch.MemberID, &sketch.Slug, &sketch.Title, &sketch.Description,
 			&sketch.Keywords, &sketch.TagsJSON, &sketch.ExternalLibsJSON, &sketch.SourceCode,
-			&sketch.CreatedAt, &sketch.UpdatedAt)
+			&sketch.ForkedFromSketchID, &sketch.Visibility, &sketch.Hidden, &sketch.CreatedAt, &sketch.UpdatedAt)
 		if err != nil {
-			log.Printf("Database error while scanning sketch: %v", err)
+			utils.LogErrorf("Database error while scanning sketch: %v", err)
 			continue
 		}
 
 		// Unmarshal JSON fields
 		if err := json.Unmarshal([]byte(sketch.TagsJSON), &sketch.Tags); err != nil {
-			log.Printf("Warning: failed to unmarshal tags for sketch %d: %v", sketch.ID, err)
+			utils.LogWarnf("Failed to unmarshal tags for sketch %d: %v", sketch.ID, err)
 			sketch.Tags = []string{} // fallback to empty slice
 		}
 		if err := json.Unmarshal([]byte(sketch.ExternalLibsJSON), &sketch.ExternalLibs); err != nil {
-			log.Printf("Warning: failed to unmarshal external libs for sketch %d: %v", sketch.ID, err)
+			utils.LogWarnf("Failed to unmarshal external libs for sketch %d: %v", sketch.ID, err)
 			sketch.ExternalLibs = []string{} // fallback to empty slice
 		}
 
@@ -286,123 +364,89 @@ func (s *Service) GetAllSketches() ([]*model.Sketch, error) {
 	return sketches, nil
 }
 
-// GetAllSketchesGroupedByMember returns all sketches grouped by member name
-func (s *Service) GetAllSketchesGroupedByMember() ([]model.MemberSketchInfo, error) {
-	rows, err := s.db.Query(`
-		SELECT s.id, s.member_id, s.slug, s.title, s.description, s.keywords, s.tags, s.external_libs, s.source_code, s.created_at, s.updated_at, m.name as member_name
-		FROM sketches s
-		JOIN members m ON s.member_id = m.id
-		ORDER BY s.updated_at DESC`)
+// GetAllSketchesGroupedByMember returns a page of public sketches grouped by member name, and the cursor of the next page.
+// Members appear in the order of their first sketch in the page.
+func (s *Service) GetAllSketchesGroupedByMember(opts *model.ListSketchesOptions) ([]model.MemberSketchInfo, string, error) {
+	sketches, nextCursor, err := s.GetAllSketchesChronological(opts)
 	if err != nil {
-		log.Printf("Database error while getting all sketches grouped by member: %v", err)
-		return nil, fmt.Errorf("failed to get all sketches grouped by member: %w", err)
+		return nil, "", err
 	}
-	defer rows.Close()
 
 	// Keep track of members in order and build result as we go
 	var result []model.MemberSketchInfo
 	memberIndices := make(map[string]int) // maps member name to index in result slice
 
-	for rows.Next() {
-		var sketch model.Sketch
-		var memberName string
-		err := rows.Scan(
-			&sketch.ID, &sketch.MemberID, &sketch.Slug, &sketch.Title, &sketch.Description,
-			&sketch.Keywords, &sketch.TagsJSON, &sketch.ExternalLibsJSON, &sketch.SourceCode,
-			&sketch.CreatedAt, &sketch.UpdatedAt, &memberName)
-		if err != nil {
-			log.Printf("Database error while scanning sketch: %v", err)
-			continue
-		}
-
-		// Unmarshal JSON fields
-		if err := json.Unmarshal([]byte(sketch.TagsJSON), &sketch.Tags); err != nil {
-			log.Printf("Warning: failed to unmarshal tags for sketch %d: %v", sketch.ID, err)
-			sketch.Tags = []string{}
-		}
-		if err := json.Unmarshal([]byte(sketch.ExternalLibsJSON), &sketch.ExternalLibs); err != nil {
-			log.Printf("Warning: failed to unmarshal external libs for sketch %d: %v", sketch.ID, err)
-			sketch.ExternalLibs = []string{}
-		}
-
-		// Convert database sketch to SketchInfo for the lister
-		sketchInfo := model.SketchInfo{
-			Slug:  sketch.Slug,
-			URL:   fmt.Sprintf("/members/%s/%s", memberName, sketch.Slug),
-			Alias: memberName,
-		}
-
-		// Set pointers for optional fields
-		if sketch.Title != "" {
-			sketchInfo.Title = &sketch.Title
-		}
-		if sketch.Description != "" {
-			sketchInfo.Description = &sketch.Description
-		}
-		if sketch.Keywords != "" {
-			sketchInfo.Keywords = &sketch.Keywords
-		}
-		if len(sketch.Tags) > 0 {
-			sketchInfo.Tags = sketch.Tags
-		}
-
+	for _, sketchInfo := range sketches {
 		// Check if we already have this member in our result
-		if index, exists := memberIndices[memberName]; exists {
+		if index, exists := memberIndices[sketchInfo.Alias]; exists {
 			// Add sketch to existing member
 			result[index].Sketches = append(result[index].Sketches, sketchInfo)
 		} else {
 			// Create new member entry
-			memberIndices[memberName] = len(result)
+			memberIndices[sketchInfo.Alias] = len(result)
 			result = append(result, model.MemberSketchInfo{
-				Name:     memberName,
+				Name:     sketchInfo.Alias,
 				Sketches: []model.SketchInfo{sketchInfo},
 			})
 		}
 	}
 
-	return result, nil
+	return result, nextCursor, nil
 }
 
-// GetAllSketchesChronological returns all sketches in chronological order (not grouped by member)
-func (s *Service) GetAllSketchesChronological() ([]model.SketchInfo, error) {
+// GetAllSketchesChronological returns a page of public sketches (not grouped by member) and the cursor of the next page.
+// Sketches are most recently updated first unless the options ask for another sort.
+func (s *Service) GetAllSketchesChronological(opts *model.ListSketchesOptions) ([]model.SketchInfo, string, error) {
+	q, err := newListQuery(opts)
+	if err != nil {
+		return nil, "", err
+	}
+	q.where(listedCondition)
+
+	// Build the clauses first, as they add the LIMIT argument
+	clauses := q.clauses()
 	rows, err := s.db.Query(`
-		SELECT s.id, s.member_id, s.slug, s.title, s.description, s.keywords, s.tags, s.external_libs, s.source_code, s.created_at, s.updated_at, m.name as member_name
+		SELECT s.id, s.member_id, s.slug, s.title, s.description, s.keywords, s.tags, s.created_at, s.updated_at, m.name as member_name
 		FROM sketches s
 		JOIN members m ON s.member_id = m.id
-		ORDER BY s.updated_at DESC`)
+		`+clauses, q.args...)
 	if err != nil {
-		log.Printf("Database error while getting all sketches chronologically: %v", err)
-		return nil, fmt.Errorf("failed to get all sketches chronologically: %w", err)
+		utils.LogErrorf("Database error while getting all sketches chronologically: %v", err)
+		return nil, "", fmt.Errorf("failed to get all sketches chronologically: %w", err)
 	}
 	defer rows.Close()
 
 	var result []model.SketchInfo
+	var last model.Sketch
+	nextCursor := ""
 	for rows.Next() {
 		var sketch model.Sketch
 		var memberName string
 		err := rows.Scan(
 			&sketch.ID, &sketch.MemberID, &sketch.Slug, &sketch.Title, &sketch.Description,
-			&sketch.Keywords, &sketch.TagsJSON, &sketch.ExternalLibsJSON, &sketch.SourceCode,
-			&sketch.CreatedAt, &sketch.UpdatedAt, &memberName)
+			&sketch.Keywords, &sketch.TagsJSON, &sketch.CreatedAt, &sketch.UpdatedAt, &memberName)
 		if err != nil {
-			log.Printf("Database error while scanning sketch: %v", err)
+			utils.LogErrorf("Database error while scanning sketch: %v", err)
 			continue
 		}
 
+		// The extra row only tells that there is a next page
+		if len(result) == q.limit {
+			nextCursor = q.nextCursor(&last)
+			break
+		}
+		last = sketch
+
 		// Unmarshal JSON fields
 		if err := json.Unmarshal([]byte(sketch.TagsJSON), &sketch.Tags); err != nil {
-			log.Printf("Warning: failed to unmarshal tags for sketch %d: %v", sketch.ID, err)
+			utils.LogWarnf("Failed to unmarshal tags for sketch %d: %v", sketch.ID, err)
 			sketch.Tags = []string{}
 		}
-		if err := json.Unmarshal([]byte(sketch.ExternalLibsJSON), &sketch.ExternalLibs); err != nil {
-			log.Printf("Warning: failed to unmarshal external libs for sketch %d: %v", sketch.ID, err)
-			sketch.ExternalLibs = []string{}
-		}
 
 		// Convert to SketchInfo for the lister
 		sketchInfo := model.SketchInfo{
 			Slug:  sketch.Slug,
-			URL:   fmt.Sprintf("/members/%s/%s", memberName, sketch.Slug),
+			URL:   fmt.Sprintf("/sketches/%s/%s", memberName, sketch.Slug),
 			Alias: memberName,
 		}
 
@@ -423,10 +467,12 @@ func (s *Service) GetAllSketchesChronological() ([]model.SketchInfo, error) {
 		result = append(result, sketchInfo)
 	}
 
-	return result, nil
+	return result, nextCursor, nil
 }
 
-// UpdateSketch updates an existing sketch
+// UpdateSketch updates an existing sketch and increments its version. When req.ExpectedVersion
+// is set and the sketch was updated since that version, it fails with a "sketch version conflict" error.
+// If the sketch no longer exists, it fails with a "sketch not found" error.
 func (s *Service) UpdateSketch(id int, req *model.UpdateSketchRequest) (*model.Sketch, error) {
 	if id <= 0 {
 		return nil, errors.New("invalid sketch ID")
@@ -459,7 +505,7 @@ func (s *Service) UpdateSketch(id int, req *model.UpdateSketchRequest) (*model.S
 			err := s.db.QueryRow("SELECT COUNT(*) FROM sketches WHERE member_id = $1 AND slug = $2 AND id != $3",
 				currentSketch.MemberID, newSlug, id).Scan(&count)
 			if err != nil {
-				log.Printf("Database error while checking slug conflict for sketch %d: %v", id, err)
+				utils.LogErrorf("Database error while checking slug conflict for sketch %d: %v", id, err)
 				return nil, fmt.Errorf("failed to check slug conflict: %w", err)
 			}
 			if count > 0 {
@@ -508,28 +554,83 @@ func (s *Service) UpdateSketch(id int, req *model.UpdateSketchRequest) (*model.S
 		setParts = append(setParts, fmt.Sprintf("source_code = $%d", paramCount))
 		args = append(args, *req.SourceCode)
 	}
+	if req.Visibility != nil {
+		if !model.IsValidVisibility(*req.Visibility) {
+			return nil, fmt.Errorf("invalid visibility '%s'", *req.Visibility)
+		}
+		paramCount++
+		setParts = append(setParts, fmt.Sprintf("visibility = $%d", paramCount))
+		args = append(args, *req.Visibility)
+	}
 
 	if len(setParts) == 0 {
 		return nil, errors.New("no fields to update")
 	}
 
-	// Always update the updated_at field
+	// Always update the updated_at field and bump the version
+	now := time.Now()
 	paramCount++
 	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", paramCount))
-	args = append(args, time.Now())
+	args = append(args, now)
+	setParts = append(setParts, "version = version + 1")
 
-	// Add the ID for the WHERE clause
+	// Add the ID (and the expected version) for the WHERE clause
 	paramCount++
 	args = append(args, id)
+	where := fmt.Sprintf("id = $%d", paramCount)
+	if req.ExpectedVersion != nil {
+		paramCount++
+		args = append(args, *req.ExpectedVersion)
+		where += fmt.Sprintf(" AND version = $%d", paramCount)
+	}
 
-	query := fmt.Sprintf("UPDATE sketches SET %s WHERE id = $%d", strings.Join(setParts, ", "), paramCount)
+	query := fmt.Sprintf("UPDATE sketches SET %s WHERE %s", strings.Join(setParts, ", "), where)
 
-	_, err = s.db.Exec(query, args...)
+	tx, err := s.db.Begin()
 	if err != nil {
-		log.Printf("Database error while updating sketch %d: %v", id, err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		utils.LogErrorf("Database error while updating sketch %d: %v", id, err)
 		return nil, fmt.Errorf("failed to update sketch: %w", err)
 	}
 
+	// No row updated means the sketch was deleted, or saved again since the expected version
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		if req.ExpectedVersion == nil {
+			return nil, errors.New("sketch not found")
+		}
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM sketches WHERE id = $1)", id).Scan(&exists); err != nil {
+			utils.LogErrorf("Database error while checking sketch %d after a failed update: %v", id, err)
+			return nil, fmt.Errorf("failed to check sketch: %w", err)
+		}
+		if !exists {
+			return nil, errors.New("sketch not found")
+		}
+		return nil, errors.New("sketch version conflict")
+	}
+
+	// Every source code save is kept as an immutable revision
+	if req.SourceCode != nil {
+		if err := s.recordRevision(tx, id, *req.SourceCode, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit sketch update: %w", err)
+	}
+	// Pages must not keep showing the previous version (or the previous slug) from the cache
+	s.cache.remove(id)
+
 	return s.GetSketchByID(id)
 }
 
@@ -541,9 +642,10 @@ func (s *Service) DeleteSketch(id int) error {
 
 	result, err := s.db.Exec("DELETE FROM sketches WHERE id = $1", id)
 	if err != nil {
-		log.Printf("Database error while deleting sketch %d: %v", id, err)
+		utils.LogErrorf("Database error while deleting sketch %d: %v", id, err)
 		return fmt.Errorf("failed to delete sketch: %w", err)
 	}
+	s.cache.remove(id)
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -568,9 +670,10 @@ func (s *Service) DeleteSketchByMemberAndSlug(memberID int, slug string) error {
 
 	result, err := s.db.Exec("DELETE FROM sketches WHERE member_id = $1 AND slug = $2", memberID, slug)
 	if err != nil {
-		log.Printf("Database error while deleting sketch for member %d, slug '%s': %v", memberID, slug, err)
+		utils.LogErrorf("Database error while deleting sketch for member %d, slug '%s': %v", memberID, slug, err)
 		return fmt.Errorf("failed to delete sketch by member and slug: %w", err)
 	}
+	s.cache.removeBySlug(memberID, slug)
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -596,7 +699,7 @@ func (s *Service) SketchExistsByMemberAndSlug(memberID int, slug string) (bool,
 	var count int
 	err := s.db.QueryRow("SELECT COUNT(*) FROM sketches WHERE member_id = $1 AND slug = $2", memberID, slug).Scan(&count)
 	if err != nil {
-		log.Printf("Database error while checking if sketch exists for member %d and slug '%s': %v", memberID, slug, err)
+		utils.LogErrorf("Database error while checking if sketch exists for member %d and slug '%s': %v", memberID, slug, err)
 		return false, fmt.Errorf("failed to check if sketch exists: %w", err)
 	}
 
@@ -640,6 +743,15 @@ func (s *Service) CreateSketchWithSlug(memberID int, req *model.CreateSketchRequ
 		return nil, fmt.Errorf("failed to marshal external libs: %w", err)
 	}
 
+	// Sketches are public unless requested otherwise
+	visibility := model.VisibilityPublic
+	if req.Visibility != "" {
+		if !model.IsValidVisibility(req.Visibility) {
+			return nil, fmt.Errorf("invalid visibility '%s'", req.Visibility)
+		}
+		visibility = req.Visibility
+	}
+
 	now := time.Now()
 	createdAt := now
 	updatedAt := now
@@ -652,19 +764,233 @@ func (s *Service) CreateSketchWithSlug(memberID int, req *model.CreateSketchRequ
 		updatedAt = *req.UpdatedAt
 	}
 
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	var id int
-	err = s.db.QueryRow(`
-		INSERT INTO sketches (member_id, slug, title, description, keywords, tags, external_libs, source_code, created_at, updated_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
-		memberID, slug, req.Title, req.Description, req.Keywords, string(tagsJSON), string(externalLibsJSON), req.SourceCode, createdAt, updatedAt).Scan(&id)
+	err = tx.QueryRow(`
+		INSERT INTO sketches (member_id, slug, title, description, keywords, tags, external_libs, source_code, forked_from_sketch_id, visibility, created_at, updated_at) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+		memberID, slug, req.Title, req.Description, req.Keywords, string(tagsJSON), string(externalLibsJSON), req.SourceCode, req.ForkedFromSketchID, visibility, createdAt, updatedAt).Scan(&id)
 	if err != nil {
-		log.Printf("Database error while creating sketch for member %d with slug '%s': %v", memberID, slug, err)
+		utils.LogErrorf("Database error while creating sketch for member %d with slug '%s': %v", memberID, slug, err)
 		return nil, fmt.Errorf("failed to create sketch: %w", err)
 	}
 
+	// Record the initial source code as the first revision
+	if err := s.recordRevision(tx, id, req.SourceCode, updatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit sketch creation: %w", err)
+	}
+
 	return s.GetSketchByID(id)
 }
 
+// GetSketchInfoByID returns lister information (member alias, slug, title) for a public sketch
+func (s *Service) GetSketchInfoByID(id int) (*model.SketchInfo, error) {
+	if id <= 0 {
+		return nil, errors.New("invalid sketch ID")
+	}
+
+	var slug, title, memberName string
+	err := s.db.QueryRow(`
+		SELECT s.slug, s.title, m.name
+		FROM sketches s
+		JOIN members m ON s.member_id = m.id
+		WHERE s.id = $1 AND `+listedCondition, id).Scan(&slug, &title, &memberName)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.New("sketch not found")
+	}
+	if err != nil {
+		utils.LogErrorf("Database error while getting sketch info by ID %d: %v", id, err)
+		return nil, fmt.Errorf("failed to get sketch info by ID: %w", err)
+	}
+
+	sketchInfo := &model.SketchInfo{
+		Slug:  slug,
+		URL:   fmt.Sprintf("/sketches/%s/%s", memberName, slug),
+		Alias: memberName,
+	}
+	if title != "" {
+		sketchInfo.Title = &title
+	}
+
+	return sketchInfo, nil
+}
+
+// GetRemixes returns the public sketches that were forked from the given sketch, newest first
+func (s *Service) GetRemixes(sketchID int) ([]model.SketchInfo, error) {
+	if sketchID <= 0 {
+		return nil, errors.New("invalid sketch ID")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT s.slug, s.title, m.name
+		FROM sketches s
+		JOIN members m ON s.member_id = m.id
+		WHERE s.forked_from_sketch_id = $1 AND `+listedCondition+`
+		ORDER BY s.created_at DESC`, sketchID)
+	if err != nil {
+		utils.LogErrorf("Database error while getting remixes of sketch %d: %v", sketchID, err)
+		return nil, fmt.Errorf("failed to get sketch remixes: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.SketchInfo
+	for rows.Next() {
+		var slug, title, memberName string
+		if err := rows.Scan(&slug, &title, &memberName); err != nil {
+			utils.LogErrorf("Database error while scanning remix of sketch %d: %v", sketchID, err)
+			continue
+		}
+
+		sketchInfo := model.SketchInfo{
+			Slug:  slug,
+			URL:   fmt.Sprintf("/sketches/%s/%s", memberName, slug),
+			Alias: memberName,
+		}
+		if title != "" {
+			sketchInfo.Title = &title
+		}
+
+		result = append(result, sketchInfo)
+	}
+
+	return result, nil
+}
+
+// ForkSketch copies a sketch's source, external libraries and metadata into another
+// member's account under the given slug, recording where it was forked from.
+// The fork keeps the original's visibility, so that unlisted or private work stays that way.
+func (s *Service) ForkSketch(original *model.Sketch, memberID int, slug string) (*model.Sketch, error) {
+	if original == nil || original.ID <= 0 {
+		return nil, errors.New("invalid sketch to fork")
+	}
+	if original.Hidden {
+		return nil, errors.New("hidden sketches cannot be forked")
+	}
+
+	forkedFromID := original.ID
+	return s.CreateSketchWithSlug(memberID, &model.CreateSketchRequest{
+		Title:              original.Title,
+		Description:        original.Description,
+		Keywords:           original.Keywords,
+		Tags:               original.Tags,
+		ExternalLibs:       original.ExternalLibs,
+		SourceCode:         original.SourceCode,
+		ForkedFromSketchID: &forkedFromID,
+		Visibility:         original.Visibility,
+	}, slug)
+}
+
+// recordRevision stores a new revision of a sketch's source code within a transaction.
+// The sketch row must already be locked by the transaction (e.g. by an INSERT or UPDATE)
+// so that concurrent saves get consecutive revision numbers.
+func (s *Service) recordRevision(tx *sql.Tx, sketchID int, sourceCode string, createdAt time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO sketch_revisions (sketch_id, revision_number, source_code, created_at)
+		SELECT $1, COALESCE(MAX(revision_number), 0) + 1, $2, $3
+		FROM sketch_revisions WHERE sketch_id = $1`,
+		sketchID, sourceCode, createdAt)
+	if err != nil {
+		utils.LogErrorf("Database error while recording revision for sketch %d: %v", sketchID, err)
+		return fmt.Errorf("failed to record sketch revision: %w", err)
+	}
+	return nil
+}
+
+// GetRevisions returns all revisions of a sketch (without source code), newest first
+func (s *Service) GetRevisions(sketchID int) ([]*model.SketchRevision, error) {
+	if sketchID <= 0 {
+		return nil, errors.New("invalid sketch ID")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, sketch_id, revision_number, created_at
+		FROM sketch_revisions WHERE sketch_id = $1 ORDER BY revision_number DESC`, sketchID)
+	if err != nil {
+		utils.LogErrorf("Database error while getting revisions for sketch %d: %v", sketchID, err)
+		return nil, fmt.Errorf("failed to get sketch revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []*model.SketchRevision
+	for rows.Next() {
+		revision := &model.SketchRevision{}
+		err := rows.Scan(&revision.ID, &revision.SketchID, &revision.RevisionNumber, &revision.CreatedAt)
+		if err != nil {
+			utils.LogErrorf("Database error while scanning revision for sketch %d: %v", sketchID, err)
+			continue
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+// GetRevision returns a single revision of a sketch, including its source code
+func (s *Service) GetRevision(sketchID, revisionNumber int) (*model.SketchRevision, error) {
+	if sketchID <= 0 {
+		return nil, errors.New("invalid sketch ID")
+	}
+	if revisionNumber <= 0 {
+		return nil, errors.New("invalid revision number")
+	}
+
+	revision := &model.SketchRevision{}
+	err := s.db.QueryRow(`
+		SELECT id, sketch_id, revision_number, source_code, created_at
+		FROM sketch_revisions WHERE sketch_id = $1 AND revision_number = $2`, sketchID, revisionNumber).Scan(
+		&revision.ID, &revision.SketchID, &revision.RevisionNumber, &revision.SourceCode, &revision.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.New("revision not found")
+	}
+	if err != nil {
+		utils.LogErrorf("Database error while getting revision %d of sketch %d: %v", revisionNumber, sketchID, err)
+		return nil, fmt.Errorf("failed to get sketch revision: %w", err)
+	}
+
+	return revision, nil
+}
+
+// DiffRevisions returns a unified diff between two revisions of a sketch
+func (s *Service) DiffRevisions(sketchID, fromRevision, toRevision int) (string, error) {
+	from, err := s.GetRevision(sketchID, fromRevision)
+	if err != nil {
+		return "", err
+	}
+	to, err := s.GetRevision(sketchID, toRevision)
+	if err != nil {
+		return "", err
+	}
+
+	return unifiedDiff(
+		fmt.Sprintf("revision %d", from.RevisionNumber),
+		fmt.Sprintf("revision %d", to.RevisionNumber),
+		from.SourceCode, to.SourceCode), nil
+}
+
+// RestoreRevision makes an old revision's source code the current one.
+// History is never rewritten: the restored source is saved as a new revision.
+func (s *Service) RestoreRevision(sketchID, revisionNumber int) (*model.Sketch, error) {
+	revision, err := s.GetRevision(sketchID, revisionNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.UpdateSketch(sketchID, &model.UpdateSketchRequest{
+		SourceCode: &revision.SourceCode,
+	})
+}
+
 // getBookclubDefaultCode returns the source code from the 'bookclub' member's sketch
 // for today's date, or the hardcoded default if not found
 func (s *Service) getBookclubDefaultCode() string {
@@ -687,7 +1013,7 @@ function draw() {
 	var bookclubMemberID int
 	err := s.db.QueryRow("SELECT id FROM members WHERE name = $1", "bookclub").Scan(&bookclubMemberID)
 	if err != nil {
-		log.Printf("Bookclub member not found or error querying: %v", err)
+		utils.LogWarnf("Bookclub member not found or error querying: %v", err)
 		return fallbackCode
 	}
 
@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Static assets are served under fingerprinted URLs, such as /assets/js/main.3f2a9c1be04d.js,
+// built from a hash of their content when the server starts. As the URL changes whenever the
+// file does, browsers can cache them forever. The plain URLs keep working for files referenced
+// from other assets (CSS @imports, ES module imports), but must be revalidated on every use.
+const (
+	assetsDir       = "assets"
+	assetHashLength = 12
+
+	immutableCacheControl  = "public, max-age=31536000, immutable"
+	revalidateCacheControl = "no-cache"
+
+	// Smaller files are not worth compressing
+	minCompressSize = 1024
+)
+
+// compressibleTypes are the file extensions that are compressed when the client accepts it
+var compressibleTypes = map[string]bool{
+	".css":  true,
+	".html": true,
+	".js":   true,
+	".json": true,
+	".map":  true,
+	".mjs":  true,
+	".svg":  true,
+	".txt":  true,
+}
+
+// Precompressed variants of an asset may be built next to it (main.js.br, main.js.gz), as the
+// Dockerfile does for brotli. Gzip variants are otherwise made when the manifest is built.
+var precompressedExtensions = map[string]string{
+	"br":   ".br",
+	"gzip": ".gz",
+}
+
+// assetFile is one static asset with its precompressed variants, keyed by content encoding
+type assetFile struct {
+	name        string // Path under the assets directory, e.g. "js/main.js"
+	hashedName  string // e.g. "js/main.3f2a9c1be04d.js"
+	hash        string
+	contentType string
+	modTime     time.Time
+	content     []byte
+	encoded     map[string][]byte
+}
+
+// assetManifest maps both the plain and the fingerprinted asset paths to their files
+type assetManifest struct {
+	byName   map[string]*assetFile
+	byHashed map[string]*assetFile
+}
+
+// Assets holds the manifest of the static assets and serves them.
+// In dev mode the manifest is built again whenever an asset changes.
+type Assets struct {
+	current atomic.Pointer[assetManifest]
+}
+
+// LoadAssets reads and hashes every file under the web assets directory
+func LoadAssets() (*Assets, error) {
+	a := &Assets{}
+	manifest, err := buildAssetManifest()
+	if err != nil {
+		return nil, err
+	}
+	a.current.Store(manifest)
+
+	if IsDevMode() {
+		go watchWebFiles([]string{assetsDir}, func() {
+			manifest, err := buildAssetManifest()
+			if err != nil {
+				log.Printf("Error reloading assets: %v", err)
+				return
+			}
+			a.current.Store(manifest)
+			log.Printf("Reloaded assets")
+		})
+	}
+	return a, nil
+}
+
+// buildAssetManifest reads every asset and its precompressed variants from the web files
+func buildAssetManifest() (*assetManifest, error) {
+	webFS := GetWebFS()
+	manifest := &assetManifest{
+		byName:   make(map[string]*assetFile),
+		byHashed: make(map[string]*assetFile),
+	}
+
+	err := fs.WalkDir(webFS, assetsDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || isPrecompressedVariant(filePath) {
+			return err
+		}
+
+		content, err := fs.ReadFile(webFS, filePath)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])[:assetHashLength]
+		name := strings.TrimPrefix(filePath, assetsDir+"/")
+		ext := path.Ext(name)
+
+		contentType := mime.TypeByExtension(ext)
+		if contentType == "" {
+			contentType = http.DetectContentType(content)
+		}
+
+		file := &assetFile{
+			name:        name,
+			hashedName:  strings.TrimSuffix(name, ext) + "." + hash + ext,
+			hash:        hash,
+			contentType: contentType,
+			modTime:     info.ModTime(),
+			content:     content,
+			encoded:     make(map[string][]byte),
+		}
+
+		if compressibleTypes[ext] && len(content) >= minCompressSize {
+			for encoding, variantExt := range precompressedExtensions {
+				if variant, err := fs.ReadFile(webFS, filePath+variantExt); err == nil {
+					file.encoded[encoding] = variant
+				}
+			}
+			if _, ok := file.encoded["gzip"]; !ok {
+				if compressed, err := gzipBytes(content); err == nil && len(compressed) < len(content) {
+					file.encoded["gzip"] = compressed
+				}
+			}
+		}
+
+		manifest.byName[file.name] = file
+		manifest.byHashed[file.hashedName] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// isPrecompressedVariant reports whether a file is a compressed copy of another asset
+func isPrecompressedVariant(filePath string) bool {
+	for _, variantExt := range precompressedExtensions {
+		if strings.HasSuffix(filePath, variantExt) {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipBytes compresses content with gzip at the best compression level
+func gzipBytes(content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(content); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// URL returns the fingerprinted URL of an asset, given its path under the assets directory.
+// It is the "asset" template function: {{ asset "js/main.js" }}.
+func (a *Assets) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if file, ok := a.current.Load().byName[name]; ok {
+		return "/" + assetsDir + "/" + file.hashedName
+	}
+
+	log.Printf("Unknown asset %q, linking to it without a fingerprint", name)
+	return "/" + assetsDir + "/" + name
+}
+
+// ServeHTTP serves an asset by its plain or fingerprinted path, with the assets prefix stripped.
+// Fingerprinted URLs are cacheable forever; plain ones must be revalidated with their ETag.
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	manifest := a.current.Load()
+	name := strings.TrimPrefix(r.URL.Path, "/")
+
+	cacheControl := immutableCacheControl
+	file, ok := manifest.byHashed[name]
+	if !ok {
+		cacheControl = revalidateCacheControl
+		if file, ok = manifest.byName[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	header := w.Header()
+	header.Set("Cache-Control", cacheControl)
+	header.Set("Content-Type", file.contentType)
+
+	content := file.content
+	etag := file.hash
+	if len(file.encoded) > 0 {
+		header.Add("Vary", "Accept-Encoding")
+		if encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), file.encoded); encoding != "" {
+			content = file.encoded[encoding]
+			// Each encoding is a different representation, so it needs its own ETag
+			etag += "-" + encoding
+			header.Set("Content-Encoding", encoding)
+		}
+	}
+	header.Set("ETag", `"`+etag+`"`)
+
+	http.ServeContent(w, r, file.name, file.modTime, bytes.NewReader(content))
+}
+
+// negotiateEncoding picks the best content encoding that the Accept-Encoding header allows
+// among the available ones, preferring brotli to gzip. It returns "" for the identity encoding.
+func negotiateEncoding(acceptEncoding string, available map[string][]byte) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		accepted[strings.ToLower(strings.TrimSpace(coding))] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{"br", "gzip"} {
+		if _, ok := available[encoding]; !ok {
+			continue
+		}
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
@@ -3,21 +3,142 @@ package utils
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters for new password hashes (OWASP recommended minimum).
+// Changing these makes PasswordNeedsRehash report existing hashes as outdated,
+// so members are upgraded transparently on their next sign-in.
+const (
+	argon2Time    uint32 = 2
+	argon2Memory  uint32 = 19 * 1024 // KiB
+	argon2Threads uint8  = 1
+	argon2KeyLen  uint32 = 32
+	argon2SaltLen        = 16
 )
 
-// HashPassword creates a hash of the password using SHA256
-func HashPassword(password string) string {
+// Limits on the parameters of stored hashes, which are checked before deriving a key: argon2.IDKey
+// panics on a zero time or thread count, and a corrupt memory cost could allocate gigabytes
+const (
+	argon2MaxTime    uint32 = 16
+	argon2MaxMemory  uint32 = 256 * 1024 // KiB
+	argon2MaxThreads uint8  = 16
+)
+
+// argon2idPrefix identifies hashes stored in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+const argon2idPrefix = "$argon2id$"
+
+// argon2idHash holds the decoded parts of an encoded argon2id hash
+type argon2idHash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// HashPassword creates a salted argon2id hash of the password in a self-describing encoded format
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword checks if the provided password matches the hash.
+// It accepts both argon2id hashes and legacy unsalted SHA-256 hex hashes.
+func VerifyPassword(password, hash string) bool {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		legacyHash := hashPasswordLegacy(password)
+		return subtle.ConstantTimeCompare([]byte(legacyHash), []byte(hash)) == 1
+	}
+
+	decoded, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return false
+	}
+
+	key := argon2.IDKey([]byte(password), decoded.salt, decoded.time, decoded.memory, decoded.threads, uint32(len(decoded.key)))
+	return subtle.ConstantTimeCompare(key, decoded.key) == 1
+}
+
+// PasswordNeedsRehash reports whether a stored hash uses a legacy format or
+// outdated parameters and should be replaced after a successful sign-in
+func PasswordNeedsRehash(hash string) bool {
+	decoded, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+	return decoded.memory != argon2Memory || decoded.time != argon2Time ||
+		decoded.threads != argon2Threads || len(decoded.key) != int(argon2KeyLen)
+}
+
+// hashPasswordLegacy creates the unsalted SHA-256 hex hash used before argon2id
+func hashPasswordLegacy(password string) string {
 	hasher := sha256.New()
 	hasher.Write([]byte(password))
 	return hex.EncodeToString(hasher.Sum(nil))
 }
 
-// VerifyPassword checks if the provided password matches the hash
-func VerifyPassword(password, hash string) bool {
-	return HashPassword(password) == hash
+// decodeArgon2idHash parses an encoded argon2id hash
+func decodeArgon2idHash(hash string) (*argon2idHash, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, fmt.Errorf("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	decoded := &argon2idHash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &decoded.memory, &decoded.time, &decoded.threads); err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	if decoded.time == 0 || decoded.time > argon2MaxTime {
+		return nil, fmt.Errorf("argon2id time %d out of range", decoded.time)
+	}
+	if decoded.threads == 0 || decoded.threads > argon2MaxThreads {
+		return nil, fmt.Errorf("argon2id threads %d out of range", decoded.threads)
+	}
+	// Argon2 needs at least 8 KiB per thread
+	if decoded.memory < 8*uint32(decoded.threads) || decoded.memory > argon2MaxMemory {
+		return nil, fmt.Errorf("argon2id memory %d out of range", decoded.memory)
+	}
+
+	var err error
+	if decoded.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if decoded.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+	if len(decoded.key) == 0 {
+		return nil, fmt.Errorf("empty argon2id key")
+	}
+
+	return decoded, nil
 }
 
 // GenerateSessionID generates a random session ID
@@ -29,8 +150,24 @@ func GenerateSessionID() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// SetSessionCookie sets the session cookie for a member
-func SetSessionCookie(w http.ResponseWriter, sessionID string) {
+// GenerateTemporaryPassword generates a random password for an organiser to hand to a member
+// (e.g. after a password reset). It avoids characters that are easy to confuse.
+func GenerateTemporaryPassword() (string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	bytes := make([]byte, 14)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	for i, b := range bytes {
+		bytes[i] = alphabet[int(b)%len(alphabet)]
+	}
+	return string(bytes), nil
+}
+
+// SetSessionCookie sets the session cookie for a member.
+// Without remember the cookie is deleted when the browser closes. Remembered sessions get a
+// long-lived cookie; either way the server expires sessions that have not been used for a while.
+func SetSessionCookie(w http.ResponseWriter, sessionID string, remember bool) {
 	cookie := &http.Cookie{
 		Name:     "session_id",
 		Value:    sessionID,
@@ -38,7 +175,9 @@ func SetSessionCookie(w http.ResponseWriter, sessionID string) {
 		HttpOnly: true,
 		Secure:   true,
 		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(24 * time.Hour),
+	}
+	if remember {
+		cookie.Expires = time.Now().Add(365 * 24 * time.Hour)
 	}
 	http.SetCookie(w, cookie)
 }
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckNotModified sets the ETag and Last-Modified validators of a response, then answers
+// 304 Not Modified when the request's If-None-Match (or, without it, If-Modified-Since)
+// header shows that the client's copy is still current. It returns true if it answered.
+// An empty etag or a zero modTime leaves out that validator.
+func CheckNotModified(w http.ResponseWriter, r *http.Request, etag string, modTime time.Time) bool {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if etag == "" || !etagListMatches(ifNoneMatch, etag) {
+			return false
+		}
+	} else if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !modTime.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		// Last-Modified only has second precision
+		if err != nil || modTime.Truncate(time.Second).After(since) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagListMatches reports whether an If-None-Match style list of entity tags contains the
+// given one, or is "*". Weak and strong tags with the same value match each other.
+func etagListMatches(list, etag string) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
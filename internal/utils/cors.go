@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+// CORS lets pages on other sites read responses from the public API.
+// Credentials are never allowed: cross-site requests are not sent with the session cookie,
+// and cookie-authenticated writes are still protected by the CSRF checks.
+
+// corsMaxAge is how long, in seconds, browsers may cache the answer to a preflight request
+const corsMaxAge = "600"
+
+// GetCORSAllowedOrigins reads the comma-separated CORS_ALLOWED_ORIGINS environment variable,
+// e.g. "https://example.com,https://other.example" or "*"
+func GetCORSAllowedOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// corsOriginAllowed reports whether pages from the origin may read responses
+func (rt *Router) corsOriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range rt.CORSAllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// setCORSHeaders allows the request's origin to read the response, if it is one of the allowed origins
+func (rt *Router) setCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	if len(rt.CORSAllowedOrigins) == 0 {
+		return
+	}
+
+	// The response depends on the Origin header, so caches must keep one copy per origin
+	w.Header().Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	if rt.corsOriginAllowed(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		// Sketch writes need the ETag to send back in If-Match
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
+	}
+}
+
+// setCORSPreflightHeaders answers a CORS preflight request with the methods allowed for the path
+func (rt *Router) setCORSPreflightHeaders(w http.ResponseWriter, r *http.Request, allow string) {
+	if r.Header.Get("Access-Control-Request-Method") == "" || !rt.corsOriginAllowed(r.Header.Get("Origin")) {
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", allow)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-Match, "+CSRFHeaderName)
+	w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+}
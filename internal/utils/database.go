@@ -13,8 +13,25 @@ var (
 	db *sql.DB
 )
 
-// InitDatabase initializes the database connection
+// InitDatabase initializes the database connection and applies pending migrations
 func InitDatabase() error {
+	if err := OpenDatabase(); err != nil {
+		return err
+	}
+
+	// Apply pending schema migrations
+	applied, err := MigrateUp(db)
+	if err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	log.Printf("Applied %d pending migrations", applied)
+
+	log.Printf("Database initialized successfully")
+	return nil
+}
+
+// OpenDatabase opens and verifies the database connection without running migrations
+func OpenDatabase() error {
 	databaseURL := os.Getenv("DATABASE_URL")
 
 	if databaseURL == "" {
@@ -34,13 +51,6 @@ func InitDatabase() error {
 	}
 
 	db = dbConn
-
-	// Create tables
-	if err = createTables(dbConn); err != nil {
-		return fmt.Errorf("failed to create tables: %w", err)
-	}
-
-	log.Printf("Database initialized successfully")
 	return nil
 }
 
@@ -59,72 +69,3 @@ func CloseDatabase() error {
 	}
 	return nil
 }
-
-// createTables creates the necessary database tables
-func createTables(db *sql.DB) error {
-	// Members table
-	membersTable := `
-	CREATE TABLE IF NOT EXISTS members (
-		id SERIAL PRIMARY KEY,
-		name TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		verified BOOLEAN DEFAULT FALSE,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	if _, err := db.Exec(membersTable); err != nil {
-		return fmt.Errorf("failed to create members table: %w", err)
-	}
-
-	// Sessions table
-	sessionsTable := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id TEXT PRIMARY KEY,
-		member_id INTEGER NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		expires_at TIMESTAMP NOT NULL,
-		FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE CASCADE
-	);`
-
-	if _, err := db.Exec(sessionsTable); err != nil {
-		return fmt.Errorf("failed to create sessions table: %w", err)
-	}
-
-	// Sketches table
-	sketchesTable := `
-	CREATE TABLE IF NOT EXISTS sketches (
-		id SERIAL PRIMARY KEY,
-		member_id INTEGER NOT NULL,
-		slug TEXT NOT NULL,
-		title TEXT NOT NULL,
-		description TEXT DEFAULT '',
-		keywords TEXT DEFAULT '',
-		tags TEXT DEFAULT '[]',
-		external_libs TEXT DEFAULT '[]',
-		source_code TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE CASCADE,
-		UNIQUE(member_id, slug)
-	);`
-
-	if _, err := db.Exec(sketchesTable); err != nil {
-		return fmt.Errorf("failed to create sketches table: %w", err)
-	}
-
-	// Index for better query performance
-	sketchesIndexes := []string{
-		"CREATE INDEX IF NOT EXISTS idx_sketches_member_id ON sketches(member_id);",
-		"CREATE INDEX IF NOT EXISTS idx_sketches_slug ON sketches(slug);",
-		"CREATE INDEX IF NOT EXISTS idx_sketches_created_at ON sketches(created_at);",
-	}
-
-	for _, indexSQL := range sketchesIndexes {
-		if _, err := db.Exec(indexSQL); err != nil {
-			return fmt.Errorf("failed to create sketches index: %w", err)
-		}
-	}
-
-	return nil
-}
@@ -3,9 +3,11 @@ package utils
 import (
 	"bufio"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // LoadEnvFile loads environment variables from a .env file at the project root.
@@ -99,3 +101,19 @@ func loadEnvFromFile(filePath string) error {
 
 	return nil
 }
+
+// GetEnvDuration reads a duration such as "30s" or "2m" from an environment variable.
+// It returns the fallback if the variable is not set or is not a valid positive duration.
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Invalid duration %q for %s, using %s", value, key, fallback)
+		return fallback
+	}
+	return duration
+}
@@ -2,51 +2,108 @@ package utils
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
 type contextKey string
 
-const pathParamsKey contextKey = "pathParams"
+const (
+	pathParamsKey   contextKey = "pathParams"
+	routePatternKey contextKey = "routePattern"
+)
 
 // Route holds information about a registered route.
 type Route struct {
-	Method  string
-	Path    string // Can contain placeholders like /path/{id}
+	Method  string // Empty to match any method
+	Path    string // Can contain placeholders like /path/{id}, or a final catch-all like /path/{rest...}
 	Handler http.HandlerFunc
 }
 
+// Middleware wraps a handler to run code before or after it, such as checking authentication.
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// chain wraps a handler in middlewares, the first of which runs first.
+func chain(handler http.HandlerFunc, middlewares []Middleware) http.HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
 // Router is a simple HTTP multiplexer.
 type Router struct {
-	routes          []Route
+	tree            *routeNode
+	middlewares     []Middleware
 	NotFoundHandler http.HandlerFunc
+	// MethodNotAllowedHandler is called when routes match the path but not the method.
+	// The Allow header is already set when it runs.
+	MethodNotAllowedHandler http.HandlerFunc
+	// CORSAllowedOrigins lists the origins that other sites' pages may read responses from
+	// ("*" for any). CORS headers are only sent when it is not empty.
+	CORSAllowedOrigins []string
+	// Observe, if set, is called after each request with the path pattern of the route
+	// that handled it (NotFoundPattern if none did), e.g. to collect metrics
+	Observe func(method, pattern string, status int, elapsed time.Duration)
+}
+
+// Patterns passed to Router.Observe for requests that no route handled
+const (
+	NotFoundPattern = "(not found)"
+	RedirectPattern = "(redirect)" // Redirects to the clean path, see Router.dispatch
+)
+
+// statusRecorder remembers the status code written through it
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
 }
 
 // NewRouter creates a new Router.
 func NewRouter() *Router {
-	return &Router{}
+	return &Router{tree: &routeNode{}}
 }
 
 // HandleFunc registers a new route with a handler function for a specific method.
+// Paths are registered without a trailing slash; requests with one are redirected.
+// It panics if the route conflicts with one registered before.
 func (rt *Router) HandleFunc(path string, handler http.HandlerFunc, method string) {
-	rt.routes = append(rt.routes, Route{
-		Method:  method,
-		Path:    path,
-		Handler: handler,
-	})
+	if rt.tree == nil {
+		rt.tree = &routeNode{}
+	}
+	if path != "/" {
+		path = strings.TrimSuffix(path, "/")
+	}
+	rt.tree.insert(path, method, handler)
+}
+
+// Use adds middlewares that run on every request, whichever route handles it (and when none does).
+func (rt *Router) Use(middlewares ...Middleware) {
+	rt.middlewares = append(rt.middlewares, middlewares...)
+}
+
+// Group returns a group of routes sharing a path prefix and middlewares.
+func (rt *Router) Group(prefix string, middlewares ...Middleware) *RouteGroup {
+	return (&RouteGroup{router: rt}).Group(prefix, middlewares...)
 }
 
-// PathPrefix registers a handler for a path prefix (used for static files).
+// PathPrefix registers a handler for every path under a prefix, whatever the method (used for static files).
 func (rt *Router) PathPrefix(prefix string, handler http.Handler) {
-	rt.routes = append(rt.routes, Route{
-		Method: "", // Match any method
-		Path:   strings.TrimSuffix(prefix, "/") + "/*",
-		Handler: func(w http.ResponseWriter, r *http.Request) {
-			handler.ServeHTTP(w, r)
-		},
-	})
+	rt.HandleFunc(strings.TrimSuffix(prefix, "/")+"/{path...}", handler.ServeHTTP, "")
 }
 
 // PathVariable extracts a path variable from the request context.
@@ -57,63 +114,178 @@ func PathVariable(r *http.Request, key string) string {
 	return ""
 }
 
+// RoutePattern returns the path pattern of the route handling the request, e.g. /members/{memberName},
+// or NotFoundPattern or RedirectPattern. It is set for middlewares added with Router.Use too.
+func RoutePattern(r *http.Request) string {
+	pattern, _ := r.Context().Value(routePatternKey).(string)
+	return pattern
+}
+
 // ServeHTTP dispatches the request to the handler whose path pattern matches.
 func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	for _, route := range rt.routes {
-		// Check method first
-		if route.Method != "" && route.Method != r.Method {
-			continue
+	if rt.Observe == nil {
+		rt.dispatch(w, r)
+		return
+	}
+
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	pattern := rt.dispatch(recorder, r)
+	rt.Observe(r.Method, pattern, recorder.status, time.Since(start))
+}
+
+// dispatch calls the handler of the route matching the request and returns the route's path pattern.
+// Requests for unclean paths (double slashes, "." or "..", or a trailing slash) are redirected
+// to the clean path when it has a route. HEAD requests are served by GET routes, OPTIONS requests
+// are answered with the allowed methods, and requests whose path matches but method does not get a 405.
+func (rt *Router) dispatch(w http.ResponseWriter, r *http.Request) string {
+	rt.setCORSHeaders(w, r)
+
+	var node *routeNode
+	params := make(map[string]string)
+	cleanedPath := cleanPath(r.URL.Path)
+	segments, trailingSlash := splitPath(cleanedPath)
+	if rt.tree != nil {
+		node = rt.tree.lookup(segments, trailingSlash, params)
+
+		// Routes have no trailing slash, so /sketches/ redirects to /sketches
+		if node == nil && trailingSlash && rt.tree.lookup(segments, false, make(map[string]string)) != nil {
+			cleanedPath = strings.TrimSuffix(cleanedPath, "/")
+			return rt.serve(w, r, RedirectPattern, nil, redirectHandler(cleanedPath))
 		}
+	}
+	if node != nil && cleanedPath != r.URL.Path {
+		return rt.serve(w, r, RedirectPattern, nil, redirectHandler(cleanedPath))
+	}
 
-		// Check if it's a prefix route (for static assets)
-		if strings.HasSuffix(route.Path, "/*") {
-			prefix := strings.TrimSuffix(route.Path, "/*")
-			if strings.HasPrefix(r.URL.Path, prefix) {
-				route.Handler(w, r)
-				return
-			}
-		} else {
-			// Check for exact path match or path with parameters
-			if pathParams, matched := matchPath(route.Path, r.URL.Path); matched {
-				ctx := context.WithValue(r.Context(), pathParamsKey, pathParams)
-				route.Handler(w, r.WithContext(ctx))
-				return
-			}
+	if node == nil {
+		// No route matched, use NotFoundHandler or default
+		notFound := rt.NotFoundHandler
+		if notFound == nil {
+			notFound = http.NotFound
 		}
+		return rt.serve(w, r, NotFoundPattern, nil, notFound)
 	}
 
-	// No route matched, use NotFoundHandler or default
-	if rt.NotFoundHandler != nil {
-		rt.NotFoundHandler(w, r)
-	} else {
-		http.NotFound(w, r)
+	if handler := node.handlerFor(r.Method); handler != nil {
+		return rt.serve(w, r, node.pattern, params, handler)
 	}
+
+	allow := allowHeader(node.methods)
+	w.Header().Set("Allow", allow)
+
+	if r.Method == http.MethodOptions {
+		rt.setCORSPreflightHeaders(w, r, allow)
+		return rt.serve(w, r, node.pattern, params, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+
+	methodNotAllowed := rt.MethodNotAllowedHandler
+	if methodNotAllowed == nil {
+		methodNotAllowed = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	}
+	return rt.serve(w, r, node.pattern, params, methodNotAllowed)
 }
 
-// matchPath checks if a route path matches a request path and extracts parameters.
-func matchPath(routePath, requestPath string) (map[string]string, bool) {
-	// Handle root path
-	if routePath == "/" && requestPath == "/" {
-		return make(map[string]string), true
+// serve calls a handler through the router's middlewares, with the route pattern and path variables
+// in the request context, and returns the pattern
+func (rt *Router) serve(w http.ResponseWriter, r *http.Request, pattern string, params map[string]string, handler http.HandlerFunc) string {
+	ctx := context.WithValue(r.Context(), routePatternKey, pattern)
+	if params != nil {
+		ctx = context.WithValue(ctx, pathParamsKey, params)
 	}
+	chain(handler, rt.middlewares)(w, r.WithContext(ctx))
+	return pattern
+}
+
+// redirectHandler sends the client to the clean version of the request path, keeping the query string.
+// Other methods than GET and HEAD get a 308, so that clients repeat them with the same body.
+func redirectHandler(cleanedPath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := cleanedPath
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
 
-	routeParts := strings.Split(strings.Trim(routePath, "/"), "/")
-	requestParts := strings.Split(strings.Trim(requestPath, "/"), "/")
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, target, status)
+	}
+}
 
-	if len(routeParts) != len(requestParts) {
-		return nil, false
+// allowHeader builds the Allow header value from the methods registered for a path
+func allowHeader(methods []string) string {
+	var allow []string
+	seen := map[string]bool{}
+	add := func(method string) {
+		if method != "" && !seen[method] {
+			seen[method] = true
+			allow = append(allow, method)
+		}
 	}
 
-	params := make(map[string]string)
-	for i, routePart := range routeParts {
-		if strings.HasPrefix(routePart, "{") && strings.HasSuffix(routePart, "}") {
-			// Extract parameter name and store value
-			paramName := strings.Trim(routePart, "{}")
-			params[paramName] = requestParts[i]
-		} else if routePart != requestParts[i] {
-			// Exact match required for non-parameter segments
-			return nil, false
+	for _, method := range methods {
+		add(method)
+		if method == http.MethodGet {
+			add(http.MethodHead)
 		}
 	}
-	return params, true
+	add(http.MethodOptions)
+	return strings.Join(allow, ", ")
+}
+
+// RouteGroup registers routes under a common path prefix, wrapped in the group's middlewares.
+type RouteGroup struct {
+	router      *Router
+	prefix      string
+	middlewares []Middleware
+}
+
+// Group returns a nested group, whose routes get both groups' prefixes and middlewares.
+func (g *RouteGroup) Group(prefix string, middlewares ...Middleware) *RouteGroup {
+	// Copy so that sibling groups never share (and overwrite) the same backing array
+	combined := make([]Middleware, 0, len(g.middlewares)+len(middlewares))
+	combined = append(combined, g.middlewares...)
+	combined = append(combined, middlewares...)
+	return &RouteGroup{router: g.router, prefix: g.prefix + prefix, middlewares: combined}
+}
+
+// With returns a group with the same prefix and extra middlewares, for routes that need more checks.
+func (g *RouteGroup) With(middlewares ...Middleware) *RouteGroup {
+	return g.Group("", middlewares...)
+}
+
+// Use adds middlewares to the group. They only apply to routes registered after the call.
+func (g *RouteGroup) Use(middlewares ...Middleware) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+// HandleFunc registers a route under the group's prefix, e.g. "/sessions" in the "/api/members/me" group.
+func (g *RouteGroup) HandleFunc(path string, handler http.HandlerFunc, method string) {
+	g.router.HandleFunc(g.prefix+path, chain(handler, g.middlewares), method)
+}
+
+// GetClientIP returns the IP address of the client making the request.
+// X-Forwarded-For is only used when TRUST_PROXY_HEADERS is "true", i.e. when the server
+// runs behind a reverse proxy that sets it; otherwise clients could spoof their address.
+func GetClientIP(r *http.Request) string {
+	if strings.ToLower(os.Getenv("TRUST_PROXY_HEADERS")) == "true" {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			// The first address is the original client
+			if ip := strings.TrimSpace(strings.Split(forwarded, ",")[0]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
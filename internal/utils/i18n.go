@@ -3,11 +3,11 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,7 +19,11 @@ type LanguageInfo struct {
 }
 
 // translations stores the unmarshalled JSON data, allowing for nested structures.
-var translations = make(map[string]interface{})
+// The map is replaced as a whole when locales are reloaded in dev mode, never modified.
+var (
+	translations   = make(map[string]interface{})
+	translationsMu sync.RWMutex
+)
 
 // supportedLanguagesInfo stores LanguageInfo structs
 var supportedLanguagesInfo = []LanguageInfo{
@@ -31,21 +35,43 @@ var defaultLanguage = "en"
 const languageCookieName = "locale"
 
 // I18nInit initializes the translation strings by loading them from JSON files.
+// In dev mode they are loaded again whenever a locale file changes.
 func I18nInit() {
-	wd, err := os.Getwd()
-	if err != nil {
-		log.Fatalf("Error getting working directory: %v", err)
+	loaded := loadTranslations()
+
+	if len(loaded) == 0 {
+		log.Fatalf("No translations loaded. Please check 'web/locales/' directory and file contents.")
+	}
+	// Ensure default language has translations loaded
+	if _, ok := loaded[defaultLanguage]; !ok {
+		log.Fatalf("Default language '%s' translations not found. Please ensure '%s.json' exists and is valid.", defaultLanguage, defaultLanguage)
 	}
 
-	// Extract just the codes for loading files
-	var langCodes []string
-	for _, langInfo := range supportedLanguagesInfo {
-		langCodes = append(langCodes, langInfo.Code)
+	translationsMu.Lock()
+	translations = loaded
+	translationsMu.Unlock()
+
+	if IsDevMode() {
+		go watchWebFiles([]string{"locales"}, func() {
+			reloaded := loadTranslations()
+			if _, ok := reloaded[defaultLanguage]; !ok {
+				log.Printf("Warning: Keeping previous translations, default language '%s' failed to load", defaultLanguage)
+				return
+			}
+			translationsMu.Lock()
+			translations = reloaded
+			translationsMu.Unlock()
+			log.Printf("Reloaded translations")
+		})
 	}
+}
 
-	for _, lang := range langCodes {
-		filePath := filepath.Join(wd, "web", "locales", lang+".json")
-		fileBytes, err := os.ReadFile(filePath)
+// loadTranslations reads the translation file of every supported language from the web files
+func loadTranslations() map[string]interface{} {
+	loaded := make(map[string]interface{})
+	for _, langInfo := range supportedLanguagesInfo {
+		filePath := "locales/" + langInfo.Code + ".json"
+		fileBytes, err := fs.ReadFile(GetWebFS(), filePath)
 		if err != nil {
 			log.Printf("Warning: Could not read translation file %s: %v. Skipping language.", filePath, err)
 			continue
@@ -56,17 +82,10 @@ func I18nInit() {
 			log.Printf("Warning: Could not parse translation file %s: %v. Skipping language.", filePath, err)
 			continue
 		}
-		translations[lang] = langTranslations
-		log.Printf("Successfully loaded translations for language: %s from %s", lang, filePath)
-	}
-
-	if len(translations) == 0 {
-		log.Fatalf("No translations loaded. Please check 'web/locales/' directory and file contents.")
-	}
-	// Ensure default language has translations loaded
-	if _, ok := translations[defaultLanguage]; !ok {
-		log.Fatalf("Default language '%s' translations not found. Please ensure '%s.json' exists and is valid.", defaultLanguage, defaultLanguage)
+		loaded[langInfo.Code] = langTranslations
+		log.Printf("Successfully loaded translations for language: %s from %s", langInfo.Code, filePath)
 	}
+	return loaded
 }
 
 // GetDefaultLanguage returns the default language code.
@@ -79,8 +98,12 @@ func GetDefaultLanguage() string {
 func Translate(lang, key string, args ...interface{}) string {
 	originalKey := key
 
+	translationsMu.RLock()
+	currentTranslations := translations
+	translationsMu.RUnlock()
+
 	getValue := func(targetLang string) (string, bool) {
-		if langData, ok := translations[targetLang]; ok {
+		if langData, ok := currentTranslations[targetLang]; ok {
 			parts := strings.Split(key, ".")
 			current := langData
 			for i, part := range parts {
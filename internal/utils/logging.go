@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// InitLogger makes slog write JSON lines to stdout at the level set by the LOG_LEVEL
+// environment variable: debug, info (the default), warn or error.
+// Messages from the standard log package go through it too, at the info level, so warn and
+// error drop them: failures must be logged with LogErrorf, LogRequestErrorf or LogWarnf instead.
+func InitLogger() {
+	level := parseLogLevel(os.Getenv("LOG_LEVEL"))
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(handler))
+}
+
+// LogErrorf logs a failure at the error level, so that it is kept whatever LOG_LEVEL is
+func LogErrorf(format string, args ...any) {
+	slog.Error(fmt.Sprintf(format, args...))
+}
+
+// LogWarnf logs something unexpected that the server could work around at the warn level
+func LogWarnf(format string, args ...any) {
+	slog.Warn(fmt.Sprintf(format, args...))
+}
+
+// LogRequestErrorf logs a failure while handling a request at the error level, with the request ID
+func LogRequestErrorf(r *http.Request, format string, args ...any) {
+	RequestLogger(r).Error(fmt.Sprintf(format, args...))
+}
+
+// parseLogLevel converts a LOG_LEVEL value to a slog level, defaulting to info
+func parseLogLevel(value string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// RequestIDHeader carries the ID that ties log lines to a request. An ID sent by a proxy
+// in front of the server is kept, so that its logs and ours can be matched.
+const RequestIDHeader = "X-Request-ID"
+
+const requestInfoKey contextKey = "requestInfo"
+
+// RequestInfo holds what the request log line reports beyond the request itself.
+// It is filled in while the request is handled, e.g. with the member once authenticated.
+type RequestInfo struct {
+	ID       string
+	MemberID int // 0 when the request is not authenticated
+}
+
+// GetOrCreateRequestID returns the request ID sent by the client if it looks safe to log,
+// or a new random one
+func GetOrCreateRequestID(r *http.Request) string {
+	if id := r.Header.Get(RequestIDHeader); validRequestID(id) {
+		return id
+	}
+
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// validRequestID accepts short IDs made of letters, digits, dashes, underscores and dots
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestInfo returns the request with info attached to its context
+func WithRequestInfo(r *http.Request, info *RequestInfo) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey, info))
+}
+
+// GetRequestInfo returns the info attached by WithRequestInfo, or nil
+func GetRequestInfo(r *http.Request) *RequestInfo {
+	info, _ := r.Context().Value(requestInfoKey).(*RequestInfo)
+	return info
+}
+
+// SetRequestMemberID records the authenticated member for the request log line
+func SetRequestMemberID(r *http.Request, memberID int) {
+	if info := GetRequestInfo(r); info != nil {
+		info.MemberID = memberID
+	}
+}
+
+// RequestLogger returns a logger that adds the request ID to every line
+func RequestLogger(r *http.Request) *slog.Logger {
+	if info := GetRequestInfo(r); info != nil {
+		return slog.Default().With("request_id", info.ID)
+	}
+	return slog.Default()
+}
@@ -19,6 +19,8 @@ type PageData struct {
 	CurrentLanguage    string
 	IsAuthenticated    bool   // Whether the current request is from an authenticated member
 	MemberName         string // Name of the authenticated member (if any)
+	CanModerate        bool   // Whether the authenticated member is a moderator or admin
+	CSRFToken          string // Token that write requests must send back (see csrfMiddleware)
 }
 
 // GetDefaultPageData initializes PageData with default values.
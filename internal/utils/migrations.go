@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// migrationsLockKey is the Postgres advisory lock key held while migrating,
+// so that two server instances never run migrations at the same time
+const migrationsLockKey int64 = 7_301_202_501
+
+// Migration is a numbered, reversible schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// MigrateUp applies all pending migrations in order and returns how many were applied
+func MigrateUp(db *sql.DB) (int, error) {
+	applied := 0
+	err := withMigrationLock(db, func(ctx context.Context, conn *sql.Conn) error {
+		appliedVersions, err := getAppliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := appliedVersions[migration.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the given number of most recently applied migrations
+// and returns how many were reverted
+func MigrateDown(db *sql.DB, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, errors.New("number of migrations to revert must be positive")
+	}
+
+	reverted := 0
+	err := withMigrationLock(db, func(ctx context.Context, conn *sql.Conn) error {
+		appliedVersions, err := getAppliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := migrations[i]
+			if _, ok := appliedVersions[migration.Version]; !ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// MigrateRedo reverts and re-applies the most recently applied migration
+func MigrateRedo(db *sql.DB) error {
+	return withMigrationLock(db, func(ctx context.Context, conn *sql.Conn) error {
+		appliedVersions, err := getAppliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			migration := migrations[i]
+			if _, ok := appliedVersions[migration.Version]; !ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			return runMigration(ctx, conn, migration, true)
+		}
+		return errors.New("no applied migrations to redo")
+	})
+}
+
+// GetMigrationStatus returns every known migration and whether it has been applied
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	appliedVersions, err := getAppliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := appliedVersions[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CountPendingMigrations returns how many known migrations have not been applied yet.
+// Unlike GetMigrationStatus it never creates the schema_migrations table, so it is safe to call from health checks.
+func CountPendingMigrations(ctx context.Context, db *sql.DB) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	appliedVersions := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return 0, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		appliedVersions[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	pending := 0
+	for _, migration := range migrations {
+		if !appliedVersions[migration.Version] {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// withMigrationLock runs fn on a dedicated connection while holding the migrations advisory lock
+func withMigrationLock(db *sql.DB, fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Blocks until any other instance has finished migrating
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationsLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migrations lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationsLockKey); err != nil {
+			log.Printf("Failed to release migrations lock: %v", err)
+		}
+	}()
+
+	return fn(ctx, conn)
+}
+
+// getAppliedVersions ensures the schema_migrations table exists and returns applied versions
+func getAppliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	_, err := conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	appliedVersions := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		appliedVersions[version] = appliedAt
+	}
+	return appliedVersions, rows.Err()
+}
+
+// runMigration applies (up) or reverts (down) a single migration in a transaction
+func runMigration(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := migration.Down
+	if up {
+		statements = migration.Up
+	}
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		return fmt.Errorf("failed to run migration %03d_%s (%s): %w", migration.Version, migration.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+			migration.Version, migration.Name, time.Now())
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %03d_%s (%s): %w", migration.Version, migration.Name, direction, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %03d_%s (%s): %w", migration.Version, migration.Name, direction, err)
+	}
+
+	log.Printf("Migration %03d_%s %s", migration.Version, migration.Name, direction)
+	return nil
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newTestRouter registers routes whose handlers write "<pattern> <variables>" in the body
+func newTestRouter(routes ...Route) *Router {
+	router := NewRouter()
+	for _, route := range routes {
+		pattern := route.Path
+		router.HandleFunc(route.Path, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", pattern, formatPathVariables(r))
+		}, route.Method)
+	}
+	return router
+}
+
+// formatPathVariables lists the path variables of a request as name=value, sorted by name
+func formatPathVariables(r *http.Request) string {
+	vars, _ := r.Context().Value(pathParamsKey).(map[string]string)
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + vars[name]
+	}
+	return strings.Join(parts, ",")
+}
+
+// serve sends a request through the router and returns the response
+func serve(router *Router, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestRouterMatching(t *testing.T) {
+	// Registered in an order that would pick the wrong route if order mattered
+	router := newTestRouter(
+		Route{Method: "GET", Path: "/sketches/{memberName}"},
+		Route{Method: "GET", Path: "/sketches/{memberName}/{sketchSlug}"},
+		Route{Method: "GET", Path: "/sketches/new"},
+		Route{Method: "GET", Path: "/sketches/{memberName}/{sketchSlug}/edit"},
+		Route{Method: "GET", Path: "/sketches/new/edit/{sketchSlug}"},
+		Route{Method: "GET", Path: "/files/{path...}"},
+		Route{Method: "GET", Path: "/files/readme"},
+		Route{Method: "GET", Path: "/"},
+	)
+
+	tests := []struct {
+		name   string
+		target string
+		want   string // Body written by the route, or "" for a 404
+	}{
+		{"root", "/", "/ "},
+		{"static beats param", "/sketches/new", "/sketches/new "},
+		{"param", "/sketches/ada", "/sketches/{memberName} memberName=ada"},
+		{"two params", "/sketches/ada/circles", "/sketches/{memberName}/{sketchSlug} memberName=ada,sketchSlug=circles"},
+		{"static after params", "/sketches/ada/circles/edit", "/sketches/{memberName}/{sketchSlug}/edit memberName=ada,sketchSlug=circles"},
+		{"backtracks from static to param", "/sketches/new/circles", "/sketches/{memberName}/{sketchSlug} memberName=new,sketchSlug=circles"},
+		{"backtracks deeper", "/sketches/new/circles/edit", "/sketches/{memberName}/{sketchSlug}/edit memberName=new,sketchSlug=circles"},
+		{"static branch still wins", "/sketches/new/edit/circles", "/sketches/new/edit/{sketchSlug} sketchSlug=circles"},
+		{"static beats catch-all", "/files/readme", "/files/readme "},
+		{"catch-all one segment", "/files/a.js", "/files/{path...} path=a.js"},
+		{"catch-all several segments", "/files/js/pages/a.js", "/files/{path...} path=js/pages/a.js"},
+		{"catch-all empty rest", "/files/", "/files/{path...} path="},
+		{"catch-all keeps trailing slash", "/files/js/", "/files/{path...} path=js/"},
+		{"no route", "/members", ""},
+		{"too many segments", "/sketches/ada/circles/edit/more", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, "GET", tt.target)
+			if tt.want == "" {
+				if w.Code != http.StatusNotFound {
+					t.Fatalf("GET %s: status %d, want 404", tt.target, w.Code)
+				}
+				return
+			}
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s: status %d, want 200", tt.target, w.Code)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("GET %s: got %q, want %q", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterRedirects(t *testing.T) {
+	router := newTestRouter(
+		Route{Method: "GET", Path: "/sketches"},
+		Route{Method: "POST", Path: "/api/sketches/{memberName}/{sketchSlug}"},
+		Route{Method: "GET", Path: "/files/{path...}"},
+	)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		status   int
+		location string
+	}{
+		{"trailing slash", "GET", "/sketches/", http.StatusMovedPermanently, "/sketches"},
+		{"trailing slash on HEAD", "HEAD", "/sketches/", http.StatusMovedPermanently, "/sketches"},
+		{"double slash", "GET", "//sketches", http.StatusMovedPermanently, "/sketches"},
+		{"dot segments", "GET", "/files/../sketches", http.StatusMovedPermanently, "/sketches"},
+		{"keeps query", "GET", "/sketches/?sort=title", http.StatusMovedPermanently, "/sketches?sort=title"},
+		{"POST keeps method", "POST", "/api/sketches/ada/./circles", http.StatusPermanentRedirect, "/api/sketches/ada/circles"},
+		{"POST trailing slash", "POST", "/api/sketches/ada/circles/", http.StatusPermanentRedirect, "/api/sketches/ada/circles"},
+		{"catch-all keeps trailing slash", "GET", "/files//js/", http.StatusMovedPermanently, "/files/js/"},
+		{"no redirect without a route", "GET", "/members/", http.StatusNotFound, ""},
+		{"no redirect to a missing route", "GET", "/nothing/../members", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, tt.method, tt.target)
+			if w.Code != tt.status {
+				t.Fatalf("%s %s: status %d, want %d", tt.method, tt.target, w.Code, tt.status)
+			}
+			if got := w.Header().Get("Location"); got != tt.location {
+				t.Errorf("%s %s: Location %q, want %q", tt.method, tt.target, got, tt.location)
+			}
+		})
+	}
+}
+
+func TestRouterMethods(t *testing.T) {
+	router := newTestRouter(
+		Route{Method: "GET", Path: "/sketches/{memberName}"},
+		Route{Method: "PUT", Path: "/sketches/{memberName}"},
+		Route{Method: "POST", Path: "/sign-in"},
+		Route{Method: "", Path: "/any"},
+	)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		allow  string // Expected Allow header, if any
+		body   string // Expected body, if checked
+	}{
+		{"GET", "GET", "/sketches/ada", http.StatusOK, "", "/sketches/{memberName} memberName=ada"},
+		{"PUT", "PUT", "/sketches/ada", http.StatusOK, "", "/sketches/{memberName} memberName=ada"},
+		{"HEAD served by GET", "HEAD", "/sketches/ada", http.StatusOK, "", ""},
+		{"OPTIONS lists methods", "OPTIONS", "/sketches/ada", http.StatusNoContent, "GET, HEAD, PUT, OPTIONS", ""},
+		{"405 lists methods", "DELETE", "/sketches/ada", http.StatusMethodNotAllowed, "GET, HEAD, PUT, OPTIONS", ""},
+		{"no HEAD without GET", "HEAD", "/sign-in", http.StatusMethodNotAllowed, "POST, OPTIONS", ""},
+		{"405 for GET", "GET", "/sign-in", http.StatusMethodNotAllowed, "POST, OPTIONS", ""},
+		{"any method", "DELETE", "/any", http.StatusOK, "", "/any "},
+		{"404 for unknown path", "DELETE", "/nothing", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, tt.method, tt.target)
+			if w.Code != tt.status {
+				t.Fatalf("%s %s: status %d, want %d", tt.method, tt.target, w.Code, tt.status)
+			}
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("%s %s: Allow %q, want %q", tt.method, tt.target, got, tt.allow)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("%s %s: got %q, want %q", tt.method, tt.target, w.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestRouterMethodNotAllowedHandler(t *testing.T) {
+	router := newTestRouter(Route{Method: "GET", Path: "/sketches"})
+	router.MethodNotAllowedHandler = func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+
+	w := serve(router, "POST", "/sketches")
+	if w.Code != http.StatusMethodNotAllowed || !strings.Contains(w.Body.String(), "Method not allowed") {
+		t.Errorf("got %d %q from the custom handler", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Allow"); got != "GET, HEAD, OPTIONS" {
+		t.Errorf("Allow %q, want it set before the custom handler runs", got)
+	}
+}
+
+func TestRouterConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []Route
+	}{
+		{"same method and path", []Route{{Method: "GET", Path: "/sketches"}, {Method: "GET", Path: "/sketches"}}},
+		{"same path after trimming the slash", []Route{{Method: "GET", Path: "/sketches"}, {Method: "GET", Path: "/sketches/"}}},
+		{"same path with another method-less route", []Route{{Method: "", Path: "/any"}, {Method: "", Path: "/any"}}},
+		{"param names differ", []Route{{Method: "GET", Path: "/sketches/{memberName}"}, {Method: "PUT", Path: "/sketches/{name}"}}},
+		{"catch-all names differ", []Route{{Method: "GET", Path: "/files/{path...}"}, {Method: "PUT", Path: "/files/{rest...}"}}},
+		{"catch-all not last", []Route{{Method: "GET", Path: "/files/{path...}/edit"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %v did not panic", tt.routes)
+				}
+			}()
+			newTestRouter(tt.routes...)
+		})
+	}
+
+	// Different methods on one path and differently named params on different paths are fine
+	newTestRouter(
+		Route{Method: "GET", Path: "/sketches/{memberName}"},
+		Route{Method: "PUT", Path: "/sketches/{memberName}"},
+		Route{Method: "GET", Path: "/members/{name}"},
+	)
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"sketches", "/sketches"},
+		{"//sketches//ada", "/sketches/ada"},
+		{"/sketches/./ada", "/sketches/ada"},
+		{"/sketches/ada/../bob", "/sketches/bob"},
+		{"/../../sketches", "/sketches"},
+		{"/sketches/", "/sketches/"},
+		{"/sketches//", "/sketches/"},
+		{"/sketches/..", "/"},
+	}
+	for _, tt := range tests {
+		if got := cleanPath(tt.in); got != tt.want {
+			t.Errorf("cleanPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
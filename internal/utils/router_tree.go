@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Routes are stored in a tree with one node per path segment, so that a request is matched
+// by walking its path once rather than by trying every route. At each level, static segments
+// take precedence over {param} segments, which take precedence over {param...} catch-alls,
+// whatever the order in which the routes were registered.
+
+// routeNode is a node of the routing tree, standing for one segment of a path pattern
+type routeNode struct {
+	static    map[string]*routeNode
+	param     *routeNode // Matches any single segment
+	catchAll  *routeNode // Matches the rest of the path; always a leaf
+	paramName string     // Variable name, for param and catch-all nodes
+
+	pattern  string                      // Path pattern of the routes ending at this node
+	handlers map[string]http.HandlerFunc // By method; "" matches any method
+	methods  []string                    // Methods in registration order, for the Allow header
+}
+
+// paramSegment returns the variable name of a {name} or {name...} pattern segment
+func paramSegment(segment string) (name string, catchAll bool, ok bool) {
+	if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+		return "", false, false
+	}
+	name = segment[1 : len(segment)-1]
+	if strings.HasSuffix(name, "...") {
+		return strings.TrimSuffix(name, "..."), true, true
+	}
+	return name, false, true
+}
+
+// insert adds a route to the tree. It panics on conflicting patterns, as routes are
+// registered at startup and a conflict is a programming error.
+func (n *routeNode) insert(pattern string, method string, handler http.HandlerFunc) {
+	segments, _ := splitPath(pattern)
+
+	node := n
+	for i, segment := range segments {
+		name, catchAll, isParam := paramSegment(segment)
+		switch {
+		case catchAll:
+			if i != len(segments)-1 {
+				panic(fmt.Sprintf("route %s: catch-all {%s...} must be the last segment", pattern, name))
+			}
+			if node.catchAll == nil {
+				node.catchAll = &routeNode{paramName: name}
+			} else if node.catchAll.paramName != name {
+				panic(fmt.Sprintf("route %s: {%s...} conflicts with {%s...} in another route", pattern, name, node.catchAll.paramName))
+			}
+			node = node.catchAll
+		case isParam:
+			if node.param == nil {
+				node.param = &routeNode{paramName: name}
+			} else if node.param.paramName != name {
+				panic(fmt.Sprintf("route %s: {%s} conflicts with {%s} in another route", pattern, name, node.param.paramName))
+			}
+			node = node.param
+		default:
+			if node.static == nil {
+				node.static = make(map[string]*routeNode)
+			}
+			child, ok := node.static[segment]
+			if !ok {
+				child = &routeNode{}
+				node.static[segment] = child
+			}
+			node = child
+		}
+	}
+
+	if _, exists := node.handlers[method]; exists {
+		panic(fmt.Sprintf("route %s %s is registered twice", method, pattern))
+	}
+	if node.handlers == nil {
+		node.handlers = make(map[string]http.HandlerFunc)
+		node.pattern = pattern
+	}
+	node.handlers[method] = handler
+	node.methods = append(node.methods, method)
+}
+
+// lookup returns the node of the route matching the path segments, or nil, and fills in
+// the path variables. It backtracks when a static or param branch leads to no route.
+func (n *routeNode) lookup(segments []string, trailingSlash bool, params map[string]string) *routeNode {
+	if len(segments) == 0 {
+		if n.handlers != nil && !trailingSlash {
+			return n
+		}
+		// A catch-all also matches an empty rest when the path ends with a slash, e.g. /assets/
+		if trailingSlash && n.catchAll != nil {
+			params[n.catchAll.paramName] = ""
+			return n.catchAll
+		}
+		return nil
+	}
+
+	segment := segments[0]
+	if child, ok := n.static[segment]; ok {
+		if found := child.lookup(segments[1:], trailingSlash, params); found != nil {
+			return found
+		}
+	}
+	if n.param != nil {
+		if found := n.param.lookup(segments[1:], trailingSlash, params); found != nil {
+			params[n.param.paramName] = segment
+			return found
+		}
+	}
+	if n.catchAll != nil {
+		rest := strings.Join(segments, "/")
+		if trailingSlash {
+			rest += "/"
+		}
+		params[n.catchAll.paramName] = rest
+		return n.catchAll
+	}
+	return nil
+}
+
+// handlerFor returns the handler of the node for a request method, or nil.
+// GET routes also answer HEAD requests; the server drops the body for them.
+func (n *routeNode) handlerFor(method string) http.HandlerFunc {
+	if handler, ok := n.handlers[method]; ok {
+		return handler
+	}
+	if method == http.MethodHead {
+		if handler, ok := n.handlers[http.MethodGet]; ok {
+			return handler
+		}
+	}
+	return n.handlers[""]
+}
+
+// walk calls fn for every node with routes, in matching precedence order
+func (n *routeNode) walk(fn func(node *routeNode)) {
+	if n.handlers != nil {
+		fn(n)
+	}
+
+	segments := make([]string, 0, len(n.static))
+	for segment := range n.static {
+		segments = append(segments, segment)
+	}
+	sort.Strings(segments)
+	for _, segment := range segments {
+		n.static[segment].walk(fn)
+	}
+
+	if n.param != nil {
+		n.param.walk(fn)
+	}
+	if n.catchAll != nil {
+		n.catchAll.walk(fn)
+	}
+}
+
+// splitPath splits a path into its segments, and tells whether it ends with a slash.
+// The root path "/" has no segments and does not count as ending with a slash.
+func splitPath(p string) ([]string, bool) {
+	trimmed := strings.Trim(p, "/")
+	if trimmed == "" {
+		return nil, false
+	}
+	return strings.Split(trimmed, "/"), strings.HasSuffix(p, "/")
+}
+
+// cleanPath removes double slashes and "." and ".." segments from a request path,
+// keeping any trailing slash
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// Routes returns the registered routes in matching precedence order
+func (rt *Router) Routes() []Route {
+	var routes []Route
+	if rt.tree == nil {
+		return routes
+	}
+
+	rt.tree.walk(func(node *routeNode) {
+		for _, method := range node.methods {
+			routes = append(routes, Route{Method: method, Path: node.pattern, Handler: node.handlers[method]})
+		}
+	})
+	return routes
+}
+
+// DumpRoutes writes one "METHOD /path/pattern" line per route, in matching precedence order, for debugging
+func (rt *Router) DumpRoutes(w io.Writer) {
+	for _, route := range rt.Routes() {
+		method := route.Method
+		if method == "" {
+			method = "*"
+		}
+		fmt.Fprintf(w, "%-7s %s\n", method, route.Path)
+	}
+}
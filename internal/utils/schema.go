@@ -0,0 +1,218 @@
+package utils
+
+// migrations is the ordered list of schema migrations.
+// Append new migrations with the next version number; never edit or reorder applied ones.
+// Statements use IF NOT EXISTS where possible so databases created before
+// versioned migrations existed can adopt them without manual changes.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_members_sessions_sketches",
+		Up: `
+		CREATE TABLE IF NOT EXISTS members (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			password_hash TEXT NOT NULL,
+			verified BOOLEAN DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			member_id INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE IF NOT EXISTS sketches (
+			id SERIAL PRIMARY KEY,
+			member_id INTEGER NOT NULL,
+			slug TEXT NOT NULL,
+			title TEXT NOT NULL,
+			description TEXT DEFAULT '',
+			keywords TEXT DEFAULT '',
+			tags TEXT DEFAULT '[]',
+			external_libs TEXT DEFAULT '[]',
+			source_code TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (member_id) REFERENCES members (id) ON DELETE CASCADE,
+			UNIQUE(member_id, slug)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_sketches_member_id ON sketches(member_id);
+		CREATE INDEX IF NOT EXISTS idx_sketches_slug ON sketches(slug);
+		CREATE INDEX IF NOT EXISTS idx_sketches_created_at ON sketches(created_at);`,
+		Down: `
+		DROP TABLE IF EXISTS sketches;
+		DROP TABLE IF EXISTS sessions;
+		DROP TABLE IF EXISTS members;`,
+	},
+	{
+		Version: 2,
+		Name:    "create_sketch_revisions",
+		Up: `
+		CREATE TABLE IF NOT EXISTS sketch_revisions (
+			id SERIAL PRIMARY KEY,
+			sketch_id INTEGER NOT NULL,
+			revision_number INTEGER NOT NULL,
+			source_code TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (sketch_id) REFERENCES sketches (id) ON DELETE CASCADE,
+			UNIQUE(sketch_id, revision_number)
+		);
+
+		-- Backfill a first revision for sketches saved before revisions existed
+		INSERT INTO sketch_revisions (sketch_id, revision_number, source_code, created_at)
+		SELECT s.id, 1, s.source_code, s.updated_at
+		FROM sketches s
+		WHERE NOT EXISTS (SELECT 1 FROM sketch_revisions r WHERE r.sketch_id = s.id);`,
+		Down: `
+		DROP TABLE IF EXISTS sketch_revisions;`,
+	},
+	{
+		Version: 3,
+		Name:    "add_sketches_forked_from",
+		Up: `
+		-- The sketch this one was remixed from (kept even if the original is deleted)
+		ALTER TABLE sketches ADD COLUMN IF NOT EXISTS forked_from_sketch_id INTEGER
+			REFERENCES sketches (id) ON DELETE SET NULL;
+
+		CREATE INDEX IF NOT EXISTS idx_sketches_forked_from ON sketches(forked_from_sketch_id);`,
+		Down: `
+		DROP INDEX IF EXISTS idx_sketches_forked_from;
+		ALTER TABLE sketches DROP COLUMN IF EXISTS forked_from_sketch_id;`,
+	},
+	{
+		Version: 4,
+		Name:    "add_sketches_visibility",
+		Up: `
+		ALTER TABLE sketches ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'public'
+			CHECK (visibility IN ('public', 'unlisted', 'private'));
+
+		CREATE INDEX IF NOT EXISTS idx_sketches_visibility ON sketches(visibility);`,
+		Down: `
+		DROP INDEX IF EXISTS idx_sketches_visibility;
+		ALTER TABLE sketches DROP COLUMN IF EXISTS visibility;`,
+	},
+	{
+		Version: 5,
+		Name:    "add_sketches_search_vectors",
+		Up: `
+		-- Weighted full-text document over sketch metadata (tags are stored as a JSON array)
+		ALTER TABLE sketches ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('english', coalesce(keywords, '') || ' ' || translate(coalesce(tags, ''), '[]",', '    ')), 'B') ||
+				setweight(to_tsvector('english', coalesce(description, '')), 'C')
+			) STORED;
+
+		-- Source code is indexed separately without stemming and truncated to stay under the tsvector size limit
+		ALTER TABLE sketches ADD COLUMN IF NOT EXISTS source_vector tsvector
+			GENERATED ALWAYS AS (to_tsvector('simple', left(source_code, 100000))) STORED;
+
+		CREATE INDEX IF NOT EXISTS idx_sketches_search_vector ON sketches USING GIN (search_vector);
+		CREATE INDEX IF NOT EXISTS idx_sketches_source_vector ON sketches USING GIN (source_vector);`,
+		Down: `
+		DROP INDEX IF EXISTS idx_sketches_source_vector;
+		DROP INDEX IF EXISTS idx_sketches_search_vector;
+		ALTER TABLE sketches DROP COLUMN IF EXISTS source_vector;
+		ALTER TABLE sketches DROP COLUMN IF EXISTS search_vector;`,
+	},
+	{
+		Version: 6,
+		Name:    "add_members_profile",
+		Up: `
+		ALTER TABLE members ADD COLUMN IF NOT EXISTS bio TEXT NOT NULL DEFAULT '';
+		ALTER TABLE members ADD COLUMN IF NOT EXISTS avatar_url TEXT NOT NULL DEFAULT '';
+		ALTER TABLE members ADD COLUMN IF NOT EXISTS links TEXT NOT NULL DEFAULT '[]';`,
+		Down: `
+		ALTER TABLE members DROP COLUMN IF EXISTS links;
+		ALTER TABLE members DROP COLUMN IF EXISTS avatar_url;
+		ALTER TABLE members DROP COLUMN IF EXISTS bio;`,
+	},
+	{
+		Version: 7,
+		Name:    "create_invite_codes",
+		Up: `
+		CREATE TABLE IF NOT EXISTS invite_codes (
+			code TEXT PRIMARY KEY,
+			preset_name TEXT,
+			max_uses INTEGER NOT NULL DEFAULT 1 CHECK (max_uses > 0),
+			uses INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP,
+			created_by INTEGER REFERENCES members (id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- The invite code a member registered with (if any)
+		ALTER TABLE members ADD COLUMN IF NOT EXISTS invite_code TEXT
+			REFERENCES invite_codes (code) ON DELETE SET NULL;
+
+		CREATE INDEX IF NOT EXISTS idx_members_unverified ON members(created_at) WHERE NOT verified;`,
+		Down: `
+		DROP INDEX IF EXISTS idx_members_unverified;
+		ALTER TABLE members DROP COLUMN IF EXISTS invite_code;
+		DROP TABLE IF EXISTS invite_codes;`,
+	},
+	{
+		Version: 8,
+		Name:    "add_roles_and_moderation",
+		Up: `
+		ALTER TABLE members ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'member'
+			CHECK (role IN ('member', 'moderator', 'admin'));
+		ALTER TABLE members ADD COLUMN IF NOT EXISTS suspended BOOLEAN NOT NULL DEFAULT FALSE;
+
+		-- Sketches hidden by a moderator are only viewable by their owner
+		ALTER TABLE sketches ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT FALSE;`,
+		Down: `
+		ALTER TABLE sketches DROP COLUMN IF EXISTS hidden;
+		ALTER TABLE members DROP COLUMN IF EXISTS suspended;
+		ALTER TABLE members DROP COLUMN IF EXISTS role;`,
+	},
+	{
+		Version: 9,
+		Name:    "create_sign_in_attempts",
+		Up: `
+		-- Failed sign-in attempts, used to rate limit password guessing by member name and by client IP
+		CREATE TABLE IF NOT EXISTS sign_in_attempts (
+			id SERIAL PRIMARY KEY,
+			member_name TEXT NOT NULL,
+			ip TEXT NOT NULL,
+			attempted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_sign_in_attempts_member_name ON sign_in_attempts(member_name, attempted_at);
+		CREATE INDEX IF NOT EXISTS idx_sign_in_attempts_ip ON sign_in_attempts(ip, attempted_at);`,
+		Down: `
+		DROP TABLE IF EXISTS sign_in_attempts;`,
+	},
+	{
+		Version: 10,
+		Name:    "add_sessions_metadata",
+		Up: `
+		ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+		ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ip TEXT NOT NULL DEFAULT '';
+		ALTER TABLE sessions ADD COLUMN IF NOT EXISTS remember BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+		CREATE INDEX IF NOT EXISTS idx_sessions_member_id ON sessions(member_id);`,
+		Down: `
+		DROP INDEX IF EXISTS idx_sessions_member_id;
+		ALTER TABLE sessions DROP COLUMN IF EXISTS last_seen_at;
+		ALTER TABLE sessions DROP COLUMN IF EXISTS remember;
+		ALTER TABLE sessions DROP COLUMN IF EXISTS ip;
+		ALTER TABLE sessions DROP COLUMN IF EXISTS user_agent;`,
+	},
+	{
+		Version: 11,
+		Name:    "add_sketches_version",
+		Up: `
+		-- Incremented on every update, so that saves based on an old version can be refused
+		ALTER TABLE sketches ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;`,
+		Down: `
+		ALTER TABLE sketches DROP COLUMN IF EXISTS version;`,
+	},
+}
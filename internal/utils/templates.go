@@ -1,48 +1,60 @@
 package utils
 
 import (
-	"io/fs"
+	"html/template"
 	"log"
-	"path/filepath"
+	"net/http"
+	"sync/atomic"
 )
 
-func GetTemplateFiles() ([]string, error) {
-	// Collect all HTML templates
-	var htmlTemplates []string
+// PageHandlerFunc renders a page. It gets its own copy of the templates and the data shared
+// by every page (language, theme, authenticated member), to fill in and pass to its template.
+type PageHandlerFunc func(w http.ResponseWriter, r *http.Request, tmpl *template.Template, pageData *PageData)
 
-	// Get all HTML templates from 'web/pages'
-	err := filepath.WalkDir("web/pages", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+// templateDirs are the web directories holding templates
+var templateDirs = []string{"pages", "components"}
 
-		// Add only .html files to the list
-		if !d.IsDir() && filepath.Ext(path) == ".html" {
-			htmlTemplates = append(htmlTemplates, path)
-		}
-		return nil
-	})
+// Templates holds the parsed page and component templates.
+// In dev mode they are parsed again whenever a template file changes.
+type Templates struct {
+	funcs   template.FuncMap
+	current atomic.Pointer[template.Template]
+}
 
+// LoadTemplates parses every page and component template from the web files
+func LoadTemplates(funcs template.FuncMap) (*Templates, error) {
+	t := &Templates{funcs: funcs}
+	tmpl, err := t.parse()
 	if err != nil {
-		log.Fatalf("Error walking through 'web/pages/_static': %v", err)
+		return nil, err
 	}
+	t.current.Store(tmpl)
+
+	if IsDevMode() {
+		go watchWebFiles(templateDirs, func() {
+			tmpl, err := t.parse()
+			if err != nil {
+				// Keep serving the previous templates until the error is fixed
+				log.Printf("Error reloading templates: %v", err)
+				return
+			}
+			t.current.Store(tmpl)
+			log.Printf("Reloaded templates")
+		})
+	}
+	return t, nil
+}
 
-	// Get all HTML templates from 'web/components'
-	err = filepath.WalkDir("web/components", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Add only .html files to the list
-		if !d.IsDir() && filepath.Ext(path) == ".html" {
-			htmlTemplates = append(htmlTemplates, path)
-		}
-		return nil
-	})
-
-	if err != nil {
-		log.Fatalf("Error walking through 'web/components': %v", err)
+// parse parses all templates from the web files
+func (t *Templates) parse() (*template.Template, error) {
+	patterns := make([]string, 0, len(templateDirs))
+	for _, dir := range templateDirs {
+		patterns = append(patterns, dir+"/*.html")
 	}
+	return template.New("").Funcs(t.funcs).ParseFS(GetWebFS(), patterns...)
+}
 
-	return htmlTemplates, nil
+// Get returns the current templates. Clone them before executing, as pages may redefine blocks.
+func (t *Templates) Get() *template.Template {
+	return t.current.Load()
 }
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"time"
+
+	"github.com/sb-luis/creative-coding-bookclub/web"
+)
+
+// The web files (templates, locales and static assets) are read from the copy embedded in the
+// binary, unless the server runs in dev mode, where they are read from disk and templates and
+// locales are reloaded whenever they change.
+var (
+	webFS   fs.FS = web.Files
+	devMode bool
+)
+
+// devReloadInterval is how often dev mode checks the web files for changes
+const devReloadInterval = time.Second
+
+// UseWebDir switches to dev mode, reading the web files from a directory on disk
+// (usually "web" in a checkout). It must be called before the files are first used.
+func UseWebDir(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	webFS = os.DirFS(dir)
+	devMode = true
+	log.Printf("Dev mode: reading web files from %s", dir)
+	return nil
+}
+
+// GetWebFS returns the web files, with the assets, components, locales and pages directories at its root
+func GetWebFS() fs.FS {
+	return webFS
+}
+
+// IsDevMode reports whether the web files are read from disk and reloaded on change
+func IsDevMode() bool {
+	return devMode
+}
+
+// watchWebFiles calls onChange whenever a file in one of the web directories is modified,
+// added or removed. It only runs in dev mode and never returns.
+func watchWebFiles(dirs []string, onChange func()) {
+	last := latestModTime(dirs)
+	for range time.Tick(devReloadInterval) {
+		if latest := latestModTime(dirs); !latest.Equal(last) {
+			last = latest
+			onChange()
+		}
+	}
+}
+
+// latestModTime returns a value that changes whenever a file in the directories changes:
+// the latest modification time, shifted by the number of files so that deletions count too
+func latestModTime(dirs []string) time.Time {
+	var latest time.Time
+	files := 0
+	for _, dir := range dirs {
+		fs.WalkDir(webFS, dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			files++
+			if info, err := d.Info(); err == nil && info.ModTime().After(latest) {
+				latest = info.ModTime()
+			}
+			return nil
+		})
+	}
+	return latest.Add(time.Duration(files))
+}
@@ -18,6 +18,12 @@ document.addEventListener('DOMContentLoaded', () => {
   }
 });
 
+// CSRF token that write requests must send in the X-CSRF-Token header
+function getCSRFToken() {
+  const meta = document.querySelector('meta[name="csrf-token"]');
+  return meta ? meta.content : '';
+}
+
 // Sign out function
 async function signOut() {
   try {
@@ -25,6 +31,7 @@ async function signOut() {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        'X-CSRF-Token': getCSRFToken(),
       },
     });
 
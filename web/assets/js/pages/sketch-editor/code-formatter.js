@@ -1,13 +1,72 @@
 import { elements, state } from './dom-elements.js';
 import { updateLineNumbers } from './line-numbers.js';
 import { updateFileSize, updateCursorPosition } from './status-tracker.js';
+import { logToConsole } from './console-manager.js';
 
-export function formatCode() {
+// Format the code on the server, so that it is formatted the same way for everyone.
+// Signed-out visitors cannot use the server, so their code is re-indented in the browser.
+export async function formatCode() {
   console.log('🛠️ Formatting code...');
 
   const code = elements.codeEditor.value;
   if (code.trim() === '') return;
 
+  let result;
+  try {
+    const response = await fetch('/api/tools/format', {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'X-CSRF-Token': getCSRFToken(),
+      },
+      body: JSON.stringify({ source_code: code }),
+    });
+
+    if (response.status === 401) {
+      result = { source_code: formatCodeLocally(code), diagnostics: [] };
+    } else if (!response.ok && response.status !== 422) {
+      // 422 means the code has syntax errors and was not formatted
+      throw new Error(`HTTP ${response.status}`);
+    } else {
+      result = await response.json();
+    }
+  } catch (error) {
+    console.error('Error formatting code:', error);
+    logToConsole('error', `Could not format the code: ${error.message}`);
+    return;
+  }
+
+  // Show syntax errors and warnings where sketch output goes
+  for (const diagnostic of result.diagnostics) {
+    const location = diagnostic.line > 0
+      ? `line ${diagnostic.line}, column ${diagnostic.column}: `
+      : '';
+    logToConsole(diagnostic.severity, `${location}${diagnostic.message}`);
+  }
+
+  // Leave the code alone if it changed while it was being formatted
+  if (elements.codeEditor.value !== code || result.source_code === code) {
+    return;
+  }
+
+  const originalScrollTop = elements.codeEditor.scrollTop;
+  const originalSelectionStart = elements.codeEditor.selectionStart;
+  const originalSelectionEnd = elements.codeEditor.selectionEnd;
+
+  elements.codeEditor.value = result.source_code;
+
+  // Restore scroll position and selection 
+  elements.codeEditor.scrollTop = originalScrollTop;
+  elements.codeEditor.setSelectionRange(originalSelectionStart, originalSelectionEnd);
+
+  // Update UI components
+  updateLineNumbers();
+  updateFileSize();
+  updateCursorPosition();
+}
+
+// Re-indent code by its braces, without checking it
+function formatCodeLocally(code) {
   // Convert tabs to spaces and normalize line endings 
   let normalized = code.split('\r\n').join('\n'); // Normalize line endings
   normalized = normalized.split('\t').join('  '); // Convert tabs to spaces
@@ -58,20 +117,7 @@ export function formatCode() {
     }
   }
 
-  const originalScrollTop = elements.codeEditor.scrollTop;
-  const originalSelectionStart = elements.codeEditor.selectionStart;
-  const originalSelectionEnd = elements.codeEditor.selectionEnd;
-
-  elements.codeEditor.value = finalLines.join('\n');
-
-  // Restore scroll position and selection 
-  elements.codeEditor.scrollTop = originalScrollTop;
-  elements.codeEditor.setSelectionRange(originalSelectionStart, originalSelectionEnd);
-
-  // Update UI components
-  updateLineNumbers();
-  updateFileSize();
-  updateCursorPosition();
+  return finalLines.join('\n');
 }
 
 export function clearCode() {
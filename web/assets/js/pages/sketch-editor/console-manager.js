@@ -7,6 +7,15 @@ export function clearConsole() {
   }
 }
 
+// Write a message to the console overlay, like the messages forwarded from the sketch
+export function logToConsole(method, text) {
+  if (elements.consoleOutput) {
+    const timestamp = new Date().toLocaleTimeString();
+    elements.consoleOutput.textContent += `[${timestamp}] ${method.toUpperCase()}: ${text}\n`;
+    elements.consoleOutput.scrollTop = elements.consoleOutput.scrollHeight;
+  }
+}
+
 export function showConsole() {
   if (elements.consoleOverlayContainer) {
     elements.consoleOverlayContainer.classList.remove('hidden');
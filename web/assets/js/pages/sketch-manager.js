@@ -33,6 +33,7 @@ const metadataDescriptionInput = document.getElementById(
 );
 const metadataKeywordsInput = document.getElementById('metadata-keywords');
 const metadataTagsInput = document.getElementById('metadata-tags');
+const metadataVisibilitySelect = document.getElementById('metadata-visibility');
 const externalLibsContainer = document.getElementById(
   'external-libs-container'
 );
@@ -144,17 +145,26 @@ async function loadSketches() {
     const memberData = await memberResponse.json();
     const memberName = memberData.name;
 
-    // Then load sketches for this member
-    const response = await fetch(`/api/sketches/${memberName}`, {
-      credentials: 'include',
-    });
+    // Then load sketches for this member, following the Link header through every page
+    const loadedSketches = [];
+    let pageUrl = `/api/sketches/${memberName}?limit=100`;
+    while (pageUrl) {
+      const response = await fetch(pageUrl, {
+        credentials: 'include',
+      });
 
-    if (!response.ok) {
-      throw new Error(`HTTP error! status: ${response.status}`);
+      if (!response.ok) {
+        throw new Error(`HTTP error! status: ${response.status}`);
+      }
+
+      const sketchData = await response.json();
+      if (Array.isArray(sketchData)) {
+        loadedSketches.push(...sketchData);
+      }
+      pageUrl = getNextPageUrl(response);
     }
 
-    const sketchData = await response.json();
-    sketches = Array.isArray(sketchData) ? sketchData : []; 
+    sketches = loadedSketches;
     updateSketchSelector();
     console.log(`Loaded ${sketches.length} sketches for ${memberName}`);
   } catch (error) {
@@ -164,6 +174,16 @@ async function loadSketches() {
   }
 }
 
+// Get the URL of the next page of a paginated API response from its Link header
+function getNextPageUrl(response) {
+  const link = response.headers.get('Link');
+  if (!link) {
+    return null;
+  }
+  const match = link.match(/<([^>]+)>;\s*rel="next"/);
+  return match ? match[1] : null;
+}
+
 // Update sketch selector dropdown
 function updateSketchSelector() {
   sketchSelector.innerHTML = '<option value="">Select a sketch...</option>';
@@ -278,6 +298,76 @@ function getCodeFromIframe() {
   return '';
 }
 
+// Replace the code in the editor, marking the sketch as changed
+function setCodeInIframe(code) {
+  try {
+    const editor = sketchIframe.contentWindow.document.getElementById('code-editor');
+    if (editor) {
+      editor.value = code;
+      editor.dispatchEvent(new Event('input', { bubbles: true }));
+    }
+  } catch (error) {
+    console.error('Error setting code in iframe:', error);
+  }
+}
+
+// Ask what to do when saving over a version of the sketch saved somewhere else.
+// Returns 'overwrite', 'merge' or 'keep' (keep editing without saving).
+function askConflictResolution() {
+  if (
+    confirm(
+      'This sketch was saved somewhere else (in another tab, or by someone you are coding with) since you opened it.\n\n' +
+        'OK: overwrite it with your version.\nCancel: see other options.'
+    )
+  ) {
+    return 'overwrite';
+  }
+  if (
+    confirm(
+      'Merge both versions in the editor? The lines that differ are marked so that you can combine them, then save again.\n\n' +
+        'Cancel: keep your version in the editor without saving.'
+    )
+  ) {
+    return 'merge';
+  }
+  return 'keep';
+}
+
+// Combine two versions of the code, keeping their common first and last lines
+// and wrapping the lines in between with conflict markers
+function mergeConflictingCode(mine, theirs) {
+  const mineLines = mine.split('\n');
+  const theirLines = theirs.split('\n');
+
+  let start = 0;
+  while (
+    start < mineLines.length &&
+    start < theirLines.length &&
+    mineLines[start] === theirLines[start]
+  ) {
+    start++;
+  }
+
+  let end = 0;
+  while (
+    end < mineLines.length - start &&
+    end < theirLines.length - start &&
+    mineLines[mineLines.length - 1 - end] === theirLines[theirLines.length - 1 - end]
+  ) {
+    end++;
+  }
+
+  return [
+    ...mineLines.slice(0, start),
+    '// <<<<<<< Your changes',
+    ...mineLines.slice(start, mineLines.length - end),
+    '// =======',
+    ...theirLines.slice(start, theirLines.length - end),
+    '// >>>>>>> Saved version',
+    ...mineLines.slice(mineLines.length - end),
+  ].join('\n');
+}
+
 // Save current sketch
 async function saveSketch() {
   console.log('🔄 Starting saveSketch function...');
@@ -359,6 +449,7 @@ async function saveSketch() {
   try {
     let response;
     let responseData;
+    let diagnostics = [];
 
     console.log('🔐 Getting current user authentication...');
     // Get the current user's name
@@ -394,14 +485,39 @@ async function saveSketch() {
         source_code: sourceCode,
       };
 
-      response = await fetch(updateUrl, {
-        method: 'PUT',
-        headers: {
-          'Content-Type': 'application/json',
-        },
-        credentials: 'include',
-        body: JSON.stringify(sourceCodeData),
-      });
+      const putSourceCode = (version) =>
+        fetch(updateUrl, {
+          method: 'PUT',
+          headers: {
+            'Content-Type': 'application/json',
+            'X-CSRF-Token': getCSRFToken(),
+            'If-Match': `"${version}"`,
+          },
+          credentials: 'include',
+          body: JSON.stringify(sourceCodeData),
+        });
+
+      response = await putSourceCode(currentSketch.version);
+
+      // The sketch was saved somewhere else (another tab, or a friend) since it was loaded
+      if (response.status === 409 || response.status === 412) {
+        const conflict = await response.json();
+        console.warn('⚠️ Save conflict, server is at version', conflict.current.version);
+
+        const resolution = askConflictResolution();
+        if (resolution === 'overwrite') {
+          response = await putSourceCode(conflict.current.version);
+        } else {
+          if (resolution === 'merge') {
+            setCodeInIframe(mergeConflictingCode(sourceCode, conflict.current.source_code));
+            // The merged code includes the saved version, so it can be saved over it
+            currentSketch = { ...currentSketch, version: conflict.current.version };
+          }
+          hasUnsavedChanges = true;
+          updateSketchStatus();
+          return;
+        }
+      }
 
       console.log('📡 Update response status:', response.status);
       console.log(
@@ -425,6 +541,10 @@ async function saveSketch() {
       responseData = await response.json();
       console.log('✅ Update successful! Response data:', responseData);
 
+      // Syntax errors and warnings found in the saved code are not part of the sketch
+      diagnostics = responseData.diagnostics || [];
+      delete responseData.diagnostics;
+
       // Update currentSketch with new data from response
       const sketchIndex = Array.isArray(sketches) ? sketches.findIndex(
         (s) => s.slug === currentSketch.slug
@@ -450,6 +570,7 @@ async function saveSketch() {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
+          'X-CSRF-Token': getCSRFToken(),
         },
         credentials: 'include',
         body: JSON.stringify(createData),
@@ -477,6 +598,10 @@ async function saveSketch() {
       responseData = await response.json();
       console.log('✅ Create successful! Response data:', responseData);
 
+      // Syntax errors and warnings found in the saved code are not part of the sketch
+      diagnostics = responseData.diagnostics || [];
+      delete responseData.diagnostics;
+
       // Add new sketch to list and set as current
       if (!Array.isArray(sketches)) {
         sketches = [];
@@ -507,7 +632,15 @@ async function saveSketch() {
 
     // Show success message to user - use the title from the response
     const sketchTitle = responseData && responseData.title ? responseData.title : (currentSketch && currentSketch.title ? currentSketch.title : 'Sketch');
-    alert(`Sketch "${sketchTitle}" saved successfully!`);
+    const syntaxErrors = diagnostics.filter((d) => d.severity === 'error');
+    if (syntaxErrors.length > 0) {
+      const errorLines = syntaxErrors
+        .map((d) => `Line ${d.line}, column ${d.column}: ${d.message}`)
+        .join('\n');
+      alert(`Sketch "${sketchTitle}" saved, but its code has syntax errors:\n\n${errorLines}`);
+    } else {
+      alert(`Sketch "${sketchTitle}" saved successfully!`);
+    }
   } catch (error) {
     console.error('💥 Error in saveSketch:', error);
     console.error('💥 Error stack:', error.stack);
@@ -564,6 +697,9 @@ async function deleteSketch() {
 
     const response = await fetch(deleteUrl, {
       method: 'DELETE',
+      headers: {
+        'X-CSRF-Token': getCSRFToken(),
+      },
       credentials: 'include',
     });
 
@@ -678,6 +814,7 @@ function showMetadataDialog() {
   metadataTagsInput.value = currentSketch.tags
     ? currentSketch.tags.join(',')
     : '';
+  metadataVisibilitySelect.value = currentSketch.visibility || 'public';
 
   // Update character counters
   updateCharacterCount(metadataTitleInput, titleCountSpan, 100);
@@ -780,6 +917,7 @@ async function updateSketchMetadata() {
     keywords: keywords,
     tags: tags,
     external_libs: externalLibs,
+    visibility: metadataVisibilitySelect.value,
   };
 
   console.log('📝 Updating metadata:', metadataData);
@@ -800,14 +938,32 @@ async function updateSketchMetadata() {
     const updateUrl = `/api/sketches/${memberName}/${currentSketch.slug}`;
     console.log('📡 Metadata update URL:', updateUrl);
 
-    const response = await fetch(updateUrl, {
-      method: 'PATCH',
-      headers: {
-        'Content-Type': 'application/json',
-      },
-      credentials: 'include',
-      body: JSON.stringify(metadataData),
-    });
+    const patchMetadata = (version) =>
+      fetch(updateUrl, {
+        method: 'PATCH',
+        headers: {
+          'Content-Type': 'application/json',
+          'X-CSRF-Token': getCSRFToken(),
+          'If-Match': `"${version}"`,
+        },
+        credentials: 'include',
+        body: JSON.stringify(metadataData),
+      });
+
+    let response = await patchMetadata(currentSketch.version);
+
+    // The sketch was saved somewhere else since it was loaded
+    if (response.status === 409 || response.status === 412) {
+      const conflict = await response.json();
+      if (
+        !confirm(
+          'This sketch was saved somewhere else since you opened it. Overwrite its details with yours?'
+        )
+      ) {
+        return;
+      }
+      response = await patchMetadata(conflict.current.version);
+    }
 
     if (!response.ok) {
       const errorText = await response.text();
@@ -0,0 +1,10 @@
+// Package web holds the page templates, components, locales and static assets of the site.
+// They are embedded in the server binary, so that it runs from any directory.
+package web
+
+import "embed"
+
+// Files contains the assets, components, locales and pages directories
+//
+//go:embed assets components locales pages
+var Files embed.FS